@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string, out io.Writer) error
+}
+
+var commands = []command{
+	{name: "search", usage: "search puzzles without opening the window", run: Search},
+	{name: "serve", usage: "serve search api over http", run: Serve},
+}
+
+// IsCommand reports whether program arguments ask for a command
+// or the usage instead of the window, other arguments are ignored
+func IsCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	if isHelp(args[0]) {
+		return true
+	}
+	for _, cmd := range commands {
+		if args[0] == cmd.name {
+			return true
+		}
+	}
+	return false
+}
+
+func isHelp(arg string) bool {
+	switch arg {
+	case "-h", "-help", "--help", "help":
+		return true
+	default:
+		return false
+	}
+}
+
+func Run(ctx context.Context, args []string) error {
+	if isHelp(args[0]) {
+		Usage(os.Stdout)
+		return nil
+	}
+	for _, cmd := range commands {
+		if args[0] == cmd.name {
+			err := cmd.run(ctx, args[1:], os.Stdout)
+			if errors.Is(err, flag.ErrHelp) {
+				return nil // usage is already printed
+			}
+			return err
+		}
+	}
+	Usage(os.Stderr)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func Usage(out io.Writer) {
+	fmt.Fprintln(out, "Usage: cops [command] [options]")
+	fmt.Fprintln(out, "Without a command the application window is opened.")
+	fmt.Fprintln(out, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", cmd.name, cmd.usage)
+	}
+}
@@ -0,0 +1,232 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/failosof/cops/core"
+)
+
+type SearchOptions struct {
+	Moves     string
+	FEN       string
+	Tags      []string
+	Family    string
+	ECO       core.ECORange
+	Pattern   core.MovePattern
+	Material  bool
+	Board     core.BoardPattern
+	Strategy  core.SearchType
+	Tolerance uint8
+	Filter    core.PuzzleFilter
+	Sort      core.SortOrder
+	Format    core.ExportFormat
+	Output    string
+}
+
+func ParseSearchOptions(args []string) (opts SearchOptions, err error) {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	flags.StringVar(&opts.Moves, "moves", "", "PGN or move list, e.g. \"1. e4 c5 2. Nf3\"")
+	flags.StringVar(&opts.FEN, "fen", "", "FEN of the position to search from")
+	tags := flags.String("tags", "", "comma separated lichess opening tags, e.g. Sicilian_Defense_Najdorf_Variation")
+	flags.StringVar(&opts.Family, "family", "", "opening family to search with all its variations, e.g. \"Sicilian Defense\"")
+	eco := flags.String("eco", "", "ECO code or range of the openings to search, e.g. B90-B99")
+	pattern := flags.String("pattern", "", "move pattern, e.g. \"1. e4 c5 2. Nf3 * 3. d4\" or \"* Bxf7+\", searched by pattern type")
+	board := flags.String("board", "", "partial board, e.g. \"Nd5 kg8 rf8 -pd6\" (- forbids the piece, . is an empty square), searched by board type")
+	strategy := flags.String("type", "moves", "search type: moves, position, transposition, pattern, structure or board")
+	flags.BoolVar(&opts.Material, "material", false, "structure search requires the same material too")
+	tolerance := flags.Uint("tolerance", 0, "plies the moves may be played earlier or later than right after the opening (0-40)")
+	turn := flags.String("turn", "either", "puzzle side to move: white, black or either")
+	maxMoves := flags.Uint("max-moves", 10, "max moves played after the search position (1-40)")
+	minRating := flags.Uint("min-rating", 0, "min puzzle rating")
+	maxRating := flags.Uint("max-rating", 0, "max puzzle rating, 0 means no limit")
+	minPopularity := flags.Int("min-popularity", -100, "min puzzle popularity (-100-100)")
+	themes := flags.String("themes", "", "comma separated required themes, e.g. fork,mateIn2")
+	excludedThemes := flags.String("exclude-themes", "", "comma separated excluded themes")
+	sort := flags.String("sort", "none", "sort order: none, rating, popularity or move")
+	format := flags.String("format", "urls", "output format: urls, csv, json, pgn or study (lichess study chapters)")
+	flags.StringVar(&opts.Output, "out", "", "output file, standard output by default")
+
+	if err = flags.Parse(args); err != nil {
+		return
+	}
+
+	if len(opts.Moves) > 0 && len(opts.FEN) > 0 {
+		err = errors.New("either moves or fen expected, not both")
+		return
+	}
+
+	opts.Tags = strings.FieldsFunc(*tags, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	if len(*eco) > 0 {
+		if opts.ECO, err = core.ParseECORange(*eco); err != nil {
+			return
+		}
+	}
+	if opts.byOpenings() && (len(opts.Moves) > 0 || len(opts.FEN) > 0) {
+		err = errors.New("tags, family and eco can't be searched together with moves or fen")
+		return
+	}
+
+	if opts.Strategy, err = core.ParseSearchType(*strategy); err != nil {
+		return
+	}
+	if opts.Strategy == core.PatternSearch {
+		if len(opts.Moves) > 0 || len(opts.FEN) > 0 || opts.byOpenings() {
+			err = errors.New("pattern can't be searched together with moves, fen or openings")
+			return
+		}
+		if opts.Pattern, err = core.ParseMovePattern(*pattern); err != nil {
+			return
+		}
+	} else if len(*pattern) > 0 {
+		err = errors.New("pattern is searched by pattern type only")
+		return
+	}
+	if opts.Strategy == core.BoardSearch {
+		if opts.Board, err = core.ParseBoardPattern(*board); err != nil {
+			return
+		}
+		if opts.Board.Empty() {
+			err = errors.New("board pattern expected")
+			return
+		}
+	} else if len(*board) > 0 {
+		err = errors.New("board is searched by board type only")
+		return
+	}
+	if len(opts.FEN) > 0 && opts.Strategy == core.MoveSequenceSearch {
+		err = errors.New("fen can't be searched by moves")
+		return
+	}
+
+	if *tolerance > 40 {
+		err = fmt.Errorf("invalid tolerance: %d", *tolerance)
+		return
+	}
+	opts.Tolerance = uint8(*tolerance)
+
+	playingTurn, err := core.ParseTurn(*turn)
+	if err != nil {
+		return
+	}
+	opts.Filter.Turn = playingTurn.ToChess()
+
+	if *maxMoves < 1 || 40 < *maxMoves {
+		err = fmt.Errorf("invalid max moves: %d", *maxMoves)
+		return
+	}
+	opts.Filter.MaxMoves = uint8(*maxMoves)
+
+	if *minRating > math.MaxUint16 || *maxRating > math.MaxUint16 {
+		err = fmt.Errorf("invalid rating range: %d-%d", *minRating, *maxRating)
+		return
+	}
+	opts.Filter.MinRating = uint16(*minRating)
+	opts.Filter.MaxRating = uint16(*maxRating)
+
+	if *minPopularity < -100 || 100 < *minPopularity {
+		err = fmt.Errorf("invalid min popularity: %d", *minPopularity)
+		return
+	}
+	if *minPopularity > -100 {
+		popularity := int8(*minPopularity)
+		opts.Filter.MinPopularity = &popularity
+	}
+
+	if opts.Filter.Themes, err = parseThemeList(*themes); err != nil {
+		return
+	}
+	if opts.Filter.ExcludedThemes, err = parseThemeList(*excludedThemes); err != nil {
+		return
+	}
+
+	if opts.Sort, err = core.ParseSortOrder(*sort); err != nil {
+		return
+	}
+
+	opts.Format, err = core.ParseExportFormat(*format)
+
+	return
+}
+
+// byOpenings tells whether the search unions the openings
+// puzzles instead of searching from the position
+func (o SearchOptions) byOpenings() bool {
+	return len(o.Tags) > 0 || len(o.Family) > 0 || !o.ECO.Empty()
+}
+
+func Search(ctx context.Context, args []string, out io.Writer) error {
+	opts, err := ParseSearchOptions(args)
+	if err != nil {
+		return err
+	}
+
+	index, err := core.LoadIndex()
+	if err != nil {
+		return fmt.Errorf("failed to load index: %w", err)
+	}
+
+	query := core.Query{
+		Tags:         opts.Tags,
+		Family:       opts.Family,
+		ECO:          opts.ECO,
+		Pattern:      opts.Pattern,
+		Material:     opts.Material,
+		Board:        opts.Board,
+		Strategy:     opts.Strategy,
+		Tolerance:    opts.Tolerance,
+		PuzzleFilter: opts.Filter,
+		Sort:         opts.Sort,
+	}
+	switch {
+	case opts.byOpenings(), opts.Strategy == core.PatternSearch, opts.Strategy == core.BoardSearch:
+	case len(opts.FEN) > 0:
+		if query.Position, err = core.ParseFEN(opts.FEN); err != nil {
+			return err
+		}
+	default:
+		if query.Game, err = core.ParsePGN(opts.Moves); err != nil {
+			return fmt.Errorf("failed to parse moves: %w", err)
+		}
+	}
+
+	start := time.Now()
+	results, err := index.CollectPuzzles(ctx, query)
+	if err != nil {
+		return err
+	}
+	slog.Info("puzzle search", "found", len(results), "took", time.Since(start))
+
+	if opts.Format == core.StudyExport && len(results) > core.StudyMaxChapters {
+		slog.Warn("lichess study fits less chapters, import the file in parts", "max", core.StudyMaxChapters)
+	}
+
+	if len(opts.Output) > 0 {
+		file, err := os.Create(opts.Output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	return index.ExportPuzzles(out, opts.Format, results)
+}
+
+func parseThemeList(s string) (core.Themes, error) {
+	names := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+	return core.ParseThemeNames(names)
+}
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"fmt"
+	"iter"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// Board is the piece placement only, it's
+// tracked without checking the moves legality
+type Board [64]chess.Piece
+
+var startingBoard = BoardFromChess(chess.StartingPosition().Board())
+
+func BoardFromChess(b *chess.Board) (board Board) {
+	for sq := range board {
+		board[sq] = b.Piece(chess.Square(sq))
+	}
+	return
+}
+
+// boards yields the board before each ply and the
+// final one, the board is reused between iterations
+func (g Game) boards() iter.Seq2[int, *Board] {
+	return func(yield func(int, *Board) bool) {
+		board := startingBoard
+		for ply, move := range g {
+			if !yield(ply, &board) {
+				return
+			}
+			board.play(move)
+		}
+		yield(len(g), &board)
+	}
+}
+
+func (b *Board) play(move Move) {
+	piece := b[move.From]
+	b[move.From] = chess.NoPiece
+	b[move.To] = piece
+	if move.Promo != chess.NoPieceType {
+		b[move.To] = chess.NewPiece(move.Promo, piece.Color())
+	}
+
+	rook := chess.NewPiece(chess.Rook, piece.Color())
+	switch {
+	case move.Tags&chess.KingSideCastle != 0:
+		b[move.To+1], b[move.To-1] = chess.NoPiece, rook
+	case move.Tags&chess.QueenSideCastle != 0:
+		b[move.To-2], b[move.To+1] = chess.NoPiece, rook
+	case move.Tags&chess.EnPassant != 0:
+		// the captured pawn is behind the target square
+		b[chess.NewSquare(move.To.File(), move.From.Rank())] = chess.NoPiece
+	}
+}
+
+// SquareRule requires the square to have the piece or, if
+// forbidden, not to have it; no piece stands for empty square
+type SquareRule struct {
+	Square    chess.Square
+	Piece     chess.Piece
+	Forbidden bool
+}
+
+func (r SquareRule) Matches(board *Board) bool {
+	return (board[r.Square] == r.Piece) != r.Forbidden
+}
+
+// String writes the rule as FEN piece letter, or . for empty square,
+// followed by the square, forbidden rules are prefixed with -
+func (r SquareRule) String() string {
+	var s strings.Builder
+	if r.Forbidden {
+		s.WriteByte('-')
+	}
+	if r.Piece == chess.NoPiece {
+		s.WriteByte('.')
+	} else {
+		s.WriteString(fenPieces[r.Piece])
+	}
+	s.WriteString(r.Square.String())
+	return s.String()
+}
+
+var squareRuleRe = regexp.MustCompile(`^(-)?([KQRBNPkqrbnp.])([a-h][1-8])$`)
+
+var fenPieces = map[chess.Piece]string{
+	chess.WhiteKing: "K", chess.WhiteQueen: "Q", chess.WhiteRook: "R",
+	chess.WhiteBishop: "B", chess.WhiteKnight: "N", chess.WhitePawn: "P",
+	chess.BlackKing: "k", chess.BlackQueen: "q", chess.BlackRook: "r",
+	chess.BlackBishop: "b", chess.BlackKnight: "n", chess.BlackPawn: "p",
+}
+
+// BoardPattern is a partial board diagram, squares
+// without rules may have any piece or be empty
+type BoardPattern []SquareRule
+
+// ParseBoardPattern reads space separated square rules, e.g.
+// "Nd5 kg8 rf8 -pd6" for a white knight on d5, black castled
+// kingside and no black pawn on d6, see SquareRule.String
+func ParseBoardPattern(s string) (p BoardPattern, err error) {
+	for _, token := range strings.Fields(s) {
+		matches := squareRuleRe.FindStringSubmatch(token)
+		if matches == nil {
+			return nil, fmt.Errorf("invalid square rule %s", token)
+		}
+
+		rule := SquareRule{Forbidden: len(matches[1]) > 0}
+		for piece, letter := range fenPieces {
+			if letter == matches[2] {
+				rule.Piece = piece
+			}
+		}
+		rule.Square = chess.NewSquare(chess.File(matches[3][0]-'a'), chess.Rank(matches[3][1]-'1'))
+		p = p.Set(rule)
+	}
+	return
+}
+
+// Set replaces the square rule
+func (p BoardPattern) Set(rule SquareRule) BoardPattern {
+	p = p.Clear(rule.Square)
+	return append(p, rule)
+}
+
+// Clear removes the square rule
+func (p BoardPattern) Clear(sq chess.Square) BoardPattern {
+	return slices.DeleteFunc(p, func(rule SquareRule) bool {
+		return rule.Square == sq
+	})
+}
+
+// Rule returns the square rule if any
+func (p BoardPattern) Rule(sq chess.Square) (rule SquareRule, ok bool) {
+	for _, rule := range p {
+		if rule.Square == sq {
+			return rule, true
+		}
+	}
+	return
+}
+
+func (p BoardPattern) Empty() bool {
+	return len(p) == 0
+}
+
+func (p BoardPattern) String() string {
+	rules := make([]string, len(p))
+	for i, rule := range p {
+		rules[i] = rule.String()
+	}
+	return strings.Join(rules, " ")
+}
+
+func (p BoardPattern) Matches(board *Board) bool {
+	for _, rule := range p {
+		if !rule.Matches(board) {
+			return false
+		}
+	}
+	return true
+}
+
+// Reached tells whether the game reached any board matching the pattern
+// from the first ply up to the last one, last is included
+func (p BoardPattern) Reached(game Game, first, last int) bool {
+	for ply, board := range game.boards() {
+		if ply > last {
+			break
+		}
+		if ply >= first && p.Matches(board) {
+			return true
+		}
+	}
+	return false
+}
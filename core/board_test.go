@@ -0,0 +1,144 @@
+package core
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestBoardPlay(t *testing.T) {
+	tests := []struct {
+		name string
+		pgn  string
+	}{
+		{"quiet moves and captures", "1. e4 d5 2. exd5 Qxd5 3. Nc3"},
+		{"kingside castling", "1. e4 e5 2. Nf3 Nf6 3. Bc4 Bc5 4. O-O O-O"},
+		{"queenside castling", "1. d4 d5 2. Nc3 Nc6 3. Bf4 Bf5 4. Qd2 Qd7 5. O-O-O O-O-O"},
+		{"white en passant", "1. e4 a6 2. e5 d5 3. exd6"},
+		{"black en passant", "1. a3 d5 2. a4 d4 3. e4 dxe3"},
+		{"promotion", "1. e4 d5 2. exd5 c6 3. dxc6 Nf6 4. cxb7 Nbd7 5. bxa8=Q"},
+		{"underpromotion", "1. h4 g5 2. hxg5 h6 3. gxh6 Nf6 4. h7 Ng8 5. hxg8=N"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game := parseTestChessGame(t, test.pgn)
+			var last Board
+			for _, board := range parseTestGame(t, test.pgn).boards() {
+				last = *board
+			}
+			if want := BoardFromChess(game.Position().Board()); last != want {
+				t.Errorf("board after %q:\n%v\nwant\n%v", test.pgn, last, want)
+			}
+		})
+	}
+}
+
+func TestBoardPatternReached(t *testing.T) {
+	// the knight is on f3 before plies 3 to 6, and on d4 after the last one
+	game := parseTestGame(t, "1. e4 e5 2. Nf3 Nc6 3. d4 exd4 4. Nxd4")
+	knight := BoardPattern{{Square: chess.F3, Piece: chess.WhiteKnight}}
+
+	tests := []struct {
+		name        string
+		pattern     BoardPattern
+		first, last int
+		want        bool
+	}{
+		{"within the window", knight, 0, 10, true},
+		{"window on the first board", knight, 3, 3, true},
+		{"window on the last board", knight, 0, 3, true},
+		{"window before the board", knight, 0, 2, false},
+		{"window after the board", knight, 7, 10, false},
+		{"window starting before ply 0", BoardPattern{{Square: chess.E2, Piece: chess.WhitePawn}}, -10, 0, true},
+		{"window ending before ply 0", BoardPattern{{Square: chess.E2, Piece: chess.WhitePawn}}, -10, -1, false},
+		{"window past the game end", BoardPattern{{Square: chess.D4, Piece: chess.WhiteKnight}}, 7, 20, true},
+		{"forbidden piece", BoardPattern{{Square: chess.E5, Piece: chess.BlackPawn, Forbidden: true}}, 2, 2, false},
+		{"empty square", BoardPattern{{Square: chess.E5, Piece: chess.NoPiece}}, 6, 6, true},
+		{"all rules match", BoardPattern{knight[0], {Square: chess.C6, Piece: chess.BlackKnight}}, 3, 4, true},
+		{"rules match on different boards", BoardPattern{knight[0], {Square: chess.D4, Piece: chess.WhiteKnight}}, 0, 10, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.pattern.Reached(game, test.first, test.last); got != test.want {
+				t.Errorf("%s reached on plies %d to %d = %v, want %v", test.pattern, test.first, test.last, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseBoardPattern(t *testing.T) {
+	tests := []struct {
+		s     string
+		want  string
+		valid bool
+	}{
+		{"Nd5 kg8 -pd6", "Nd5 kg8 -pd6", true},
+		{".e4", ".e4", true},
+		{"Nd5 Bd5", "Bd5", true}, // the rule is replaced
+		{"", "", true},
+		{"Nd9", "", false},
+		{"Xd5", "", false},
+		{"+Nd5", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			pattern, err := ParseBoardPattern(test.s)
+			if valid := err == nil; valid != test.valid {
+				t.Fatalf("ParseBoardPattern(%q) error = %v, want valid %v", test.s, err, test.valid)
+			}
+			if got := pattern.String(); got != test.want {
+				t.Errorf("ParseBoardPattern(%q) = %q, want %q", test.s, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSearchPuzzlesByBoard(t *testing.T) {
+	const line = "1. e4 e5 2. Nf3 Nc6 3. d4 exd4 4. Nxd4 Nf6 5. Nc3 Bb4 6. Nxc6 bxc6 7. Bd3 d5 8. exd5 cxd5"
+	index := newTestIndex(t)
+	addTestGame(t, index, "game0001", "Scotch_Game", line, 3)
+	addTestGame(t, index, "game0002", "Scotch_Game", line, 9)
+
+	tests := []struct {
+		name     string
+		pattern  string
+		maxMoves uint8
+		want     []string
+	}{
+		{"window before ply 0", "Nf3", 10, []string{"e0001", "e0002"}},
+		{"board too far before the puzzle", "Nf3", 3, []string{"e0001"}},
+		{"board after the puzzle", "Nc3", 10, []string{"e0002"}},
+		{"board not reached", "Nf3 Nc3", 10, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pattern, err := ParseBoardPattern(test.pattern)
+			if err != nil {
+				t.Fatalf("invalid test pattern %q: %v", test.pattern, err)
+			}
+			query := Query{
+				Board:        pattern,
+				Strategy:     BoardSearch,
+				PuzzleFilter: PuzzleFilter{MaxMoves: test.maxMoves},
+			}
+			results, err := index.CollectPuzzles(context.Background(), query)
+			if err != nil {
+				t.Fatalf("CollectPuzzles() error = %v", err)
+			}
+			var got []string
+			for _, puzzle := range results {
+				got = append(got, puzzle.ID.String())
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("found %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/failosof/cops/resources"
+	"github.com/notnil/chess"
+)
+
+const CollectionsDir = "collections"
+
+// Collection is a named search saved along with its results,
+// so a re-run can be compared with them after index rebuild
+type Collection struct {
+	Name    string       `json:"name"`
+	Saved   time.Time    `json:"saved"`
+	Search  SavedSearch  `json:"search"`
+	Puzzles []PuzzleData `json:"puzzles"`
+}
+
+// SavedSearch is a query of the moves played from the starting
+// or the set up position in a form it's stored on disk
+type SavedSearch struct {
+	FEN            string   `json:"fen,omitempty"`      // starting position if empty
+	Moves          []string `json:"moves"`              // uci notation
+	Position       bool     `json:"position,omitempty"` // searched from the position, not by moves
+	Type           string   `json:"type"`
+	Tolerance      uint8    `json:"tolerance"`
+	Pattern        string   `json:"pattern,omitempty"`
+	Material       bool     `json:"material,omitempty"`
+	Board          string   `json:"board,omitempty"`
+	Turn           string   `json:"turn"`
+	MaxMoves       uint8    `json:"max_moves"`
+	MinRating      uint16   `json:"min_rating"`
+	MaxRating      uint16   `json:"max_rating"`
+	MinPopularity  *int8    `json:"min_popularity,omitempty"`
+	Themes         []string `json:"themes"`
+	ExcludedThemes []string `json:"excluded_themes"`
+	Sort           string   `json:"sort"`
+}
+
+func NewSavedSearch(q Query) (s SavedSearch, err error) {
+	switch {
+	case q.Position != nil:
+		s.FEN = q.Position.String()
+		s.Position = true
+	case q.Game != nil:
+		if start := q.Game.Positions()[0].String(); start != startingFEN {
+			s.FEN = start
+		}
+		for _, move := range q.Game.Moves() {
+			s.Moves = append(s.Moves, move.String())
+		}
+	default:
+		err = fmt.Errorf("only searches by moves or position can be saved")
+		return
+	}
+
+	switch q.Strategy {
+	case MoveSequenceSearch:
+		s.Type = "moves"
+	case PositionSearch:
+		s.Type = "position"
+	case TranspositionSearch:
+		s.Type = "transposition"
+	case PatternSearch:
+		s.Type = "pattern"
+		s.Pattern = q.Pattern.String()
+	case StructureSearch:
+		s.Type = "structure"
+		s.Material = q.Material
+	case BoardSearch:
+		s.Type = "board"
+		s.Board = q.Board.String()
+	default:
+		panic("unreachable")
+	}
+
+	s.Tolerance = q.Tolerance
+	s.Turn = TurnFromChess(q.Turn).String()
+	s.MaxMoves = q.MaxMoves
+	s.MinRating = q.MinRating
+	s.MaxRating = q.MaxRating
+	s.MinPopularity = q.MinPopularity
+	s.Themes = strings.Fields(q.Themes.String())
+	s.ExcludedThemes = strings.Fields(q.ExcludedThemes.String())
+	s.Sort = q.Sort.String()
+
+	return
+}
+
+// Query replays the moves from the saved position, search
+// from the position itself gets the game set up from it too
+func (s SavedSearch) Query() (q Query, err error) {
+	game := chess.NewGame()
+	if len(s.FEN) > 0 {
+		opt, err := chess.FEN(s.FEN)
+		if err != nil {
+			return q, fmt.Errorf("invalid saved fen: %w", err)
+		}
+		game = chess.NewGame(opt)
+	}
+	var notation chess.UCINotation
+	for _, str := range s.Moves {
+		move, err := notation.Decode(game.Position(), str)
+		if err != nil {
+			return q, fmt.Errorf("invalid saved move %s: %w", str, err)
+		}
+		if err := game.Move(move); err != nil {
+			return q, fmt.Errorf("invalid saved move %s: %w", str, err)
+		}
+	}
+	q.Game = game
+	if s.Position {
+		q.Position = game.Position()
+	}
+
+	if q.Strategy, err = ParseSearchType(s.Type); err != nil {
+		return
+	}
+	if len(s.Pattern) > 0 {
+		if q.Pattern, err = ParseMovePattern(s.Pattern); err != nil {
+			return
+		}
+	}
+	if q.Board, err = ParseBoardPattern(s.Board); err != nil {
+		return
+	}
+	q.Material = s.Material
+	q.Tolerance = s.Tolerance
+	turn, err := ParseTurn(s.Turn)
+	if err != nil {
+		return
+	}
+	q.Turn = turn.ToChess()
+	q.MaxMoves = s.MaxMoves
+	q.MinRating = s.MinRating
+	q.MaxRating = s.MaxRating
+	q.MinPopularity = s.MinPopularity
+	if q.Themes, err = ParseThemeNames(s.Themes); err != nil {
+		return
+	}
+	if q.ExcludedThemes, err = ParseThemeNames(s.ExcludedThemes); err != nil {
+		return
+	}
+	q.Sort, err = ParseSortOrder(s.Sort)
+
+	return
+}
+
+// Diff compares re-run results with the saved ones
+func (c Collection) Diff(results []PuzzleData) (added, removed []PuzzleData) {
+	saved := make(map[PuzzleID]struct{}, len(c.Puzzles))
+	for _, puzzle := range c.Puzzles {
+		saved[puzzle.ID] = struct{}{}
+	}
+
+	found := make(map[PuzzleID]struct{}, len(results))
+	for _, puzzle := range results {
+		found[puzzle.ID] = struct{}{}
+		if _, ok := saved[puzzle.ID]; !ok {
+			added = append(added, puzzle)
+		}
+	}
+
+	for _, puzzle := range c.Puzzles {
+		if _, ok := found[puzzle.ID]; !ok {
+			removed = append(removed, puzzle)
+		}
+	}
+
+	return
+}
+
+func SaveCollection(c Collection) error {
+	if len(strings.TrimSpace(c.Name)) == 0 {
+		return fmt.Errorf("collection name is empty")
+	}
+	return resources.SaveConfig(collectionFilename(c.Name), c)
+}
+
+func LoadCollection(name string) (c Collection, err error) {
+	c, err = resources.LoadConfig[Collection](collectionFilename(name))
+	if err == nil && c.Name != name {
+		err = fmt.Errorf("collection %q not found", name)
+	}
+	return
+}
+
+// ListCollections returns names of the saved collections
+func ListCollections() ([]string, error) {
+	files, err := resources.ListConfigs(CollectionsDir, ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		name, err := url.QueryUnescape(strings.TrimSuffix(file, ".json"))
+		if err != nil {
+			continue // not saved by us
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names, nil
+}
+
+// collectionFilename escapes the name, as it's entered by user
+func collectionFilename(name string) string {
+	return filepath.Join(CollectionsDir, url.QueryEscape(name)+".json")
+}
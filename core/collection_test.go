@@ -0,0 +1,58 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestSavedSearchQuery(t *testing.T) {
+	const setUp = "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"
+	position := parseTestChessGame(t, `[FEN "`+setUp+`"]`).Position()
+
+	tests := []struct {
+		name     string
+		query    Query
+		fen      string // saved start position
+		position bool
+	}{
+		{"moves", Query{Game: parseTestChessGame(t, italianGame), Strategy: MoveSequenceSearch}, "", false},
+		{"pgn with fen", Query{Game: parseTestChessGame(t, `[FEN "`+setUp+`"] 3. Bc4 Bc5`), Strategy: PositionSearch}, setUp, false},
+		{"set up position", Query{Position: position, Strategy: PositionSearch}, setUp, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.query.PuzzleFilter = PuzzleFilter{Turn: chess.White, MaxMoves: 10}
+			saved, err := NewSavedSearch(test.query)
+			if err != nil {
+				t.Fatalf("NewSavedSearch() error = %v", err)
+			}
+			if saved.FEN != test.fen || saved.Position != test.position {
+				t.Errorf("saved fen %q, position %v, want %q, %v", saved.FEN, saved.Position, test.fen, test.position)
+			}
+
+			query, err := saved.Query()
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			want := test.query.Position
+			if want == nil {
+				want = test.query.Game.Position()
+			}
+			if query.Game.Position().String() != want.String() {
+				t.Errorf("replayed to %s, want %s", query.Game.Position(), want)
+			}
+			if (query.Position != nil) != test.position {
+				t.Errorf("query position %v, want set %v", query.Position, test.position)
+			}
+			if query.Strategy != test.query.Strategy || query.PuzzleFilter.MaxMoves != 10 {
+				t.Errorf("query = %+v, want %+v", query, test.query)
+			}
+		})
+	}
+
+	if _, err := NewSavedSearch(Query{Strategy: PositionSearch}); err == nil {
+		t.Errorf("search without moves and position saved")
+	}
+}
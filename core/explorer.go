@@ -0,0 +1,107 @@
+package core
+
+import (
+	"slices"
+	"strings"
+)
+
+// OpeningNode is a family or a variation in the openings tree,
+// it has no line if the database names only deeper variations
+type OpeningNode struct {
+	Name     string
+	Path     []string
+	Opening  *Opening
+	Children []*OpeningNode
+
+	puzzles int // counted on demand, -1 until then
+}
+
+func (n *OpeningNode) Depth() int {
+	return len(n.Path) - 1
+}
+
+// OpeningTree groups the openings by family and variations,
+// the tree is built on the first call
+func (s *Index) OpeningTree() *OpeningNode {
+	s.openingTreeOnce.Do(s.buildOpeningTree)
+	return s.openingTree
+}
+
+func (s *Index) buildOpeningTree() {
+	root := &OpeningNode{puzzles: -1}
+	for _, opening := range s.Openings {
+		node := root
+		for _, name := range opening.Name.Path() {
+			node = node.child(name)
+		}
+		// same variation can be reached by several lines,
+		// the shortest one is the main
+		if node.Opening == nil || len(opening.Moves) < len(node.Opening.Moves) {
+			node.Opening = &opening
+		}
+	}
+	root.sort()
+	s.openingTree = root
+}
+
+func (n *OpeningNode) child(name string) *OpeningNode {
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	child := &OpeningNode{
+		Name:    name,
+		Path:    append(slices.Clip(n.Path), name),
+		puzzles: -1,
+	}
+	n.Children = append(n.Children, child)
+	return child
+}
+
+func (n *OpeningNode) sort() {
+	slices.SortFunc(n.Children, func(a, b *OpeningNode) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, child := range n.Children {
+		child.sort()
+	}
+}
+
+// OpeningPuzzles counts puzzles of the tree node, lichess tags puzzles
+// with family and the first variation only, so deeper variations are
+// counted by the source games passing through the variation position
+func (s *Index) OpeningPuzzles(node *OpeningNode) int {
+	if node.puzzles >= 0 {
+		return node.puzzles
+	}
+
+	switch {
+	case node.Depth() < 0:
+		node.puzzles = 0
+	case node.Depth() == 0:
+		node.puzzles = len(s.Puzzles[sanitizeOpeningName(node.Name)])
+	case node.Depth() == 1:
+		name := NewOpeningName("", node.Path[0], node.Path[1])
+		node.puzzles = len(s.Puzzles[name.Tag()])
+	case node.Opening == nil:
+		node.puzzles = 0
+	default:
+		game, err := node.Opening.Game()
+		if err != nil {
+			node.puzzles = 0
+			break
+		}
+		hash := PositionFromChess(game.Position()).Hash()
+		name := NewOpeningName("", node.Path[0], node.Path[1])
+		node.puzzles = 0
+		for _, puzzle := range s.Puzzles[name.Tag()] {
+			if len(s.Positions) == 0 && s.Games[puzzle.GameID].ContainsPosition(game.Position()) ||
+				s.Positions.Contains(hash, puzzle.GameID) {
+				node.puzzles++
+			}
+		}
+	}
+
+	return node.puzzles
+}
@@ -0,0 +1,197 @@
+package core
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type ExportFormat int8
+
+const (
+	URLsExport ExportFormat = iota
+	CSVExport
+	JSONExport
+	PGNExport
+	StudyExport
+)
+
+func (f ExportFormat) String() string {
+	switch f {
+	case URLsExport:
+		return "urls"
+	case CSVExport:
+		return "csv"
+	case JSONExport:
+		return "json"
+	case PGNExport:
+		return "pgn"
+	case StudyExport:
+		return "study"
+	default:
+		panic("unreachable")
+	}
+}
+
+func ParseExportFormat(s string) (f ExportFormat, err error) {
+	switch strings.ToLower(s) {
+	case "urls":
+		f = URLsExport
+	case "csv":
+		f = CSVExport
+	case "json":
+		f = JSONExport
+	case "pgn":
+		f = PGNExport
+	case "study":
+		f = StudyExport
+	default:
+		err = fmt.Errorf("invalid export format: %s", s)
+	}
+	return
+}
+
+// Ext returns the file extension for the format
+func (f ExportFormat) Ext() string {
+	switch f {
+	case URLsExport:
+		return ".txt"
+	case StudyExport:
+		return ".pgn"
+	default:
+		return "." + f.String()
+	}
+}
+
+// StudyMaxChapters is how many chapters lichess allows in
+// one study, bigger exports have to be imported in parts
+const StudyMaxChapters = 64
+
+// ExportPuzzles writes the puzzles in the format, pgn formats
+// need the solutions, so puzzles without one are skipped
+func (s *Index) ExportPuzzles(out io.Writer, format ExportFormat, puzzles []PuzzleData) error {
+	switch format {
+	case URLsExport:
+		for _, puzzle := range puzzles {
+			if _, err := fmt.Fprintln(out, puzzle.URL()); err != nil {
+				return fmt.Errorf("failed to write puzzle: %w", err)
+			}
+		}
+	case CSVExport:
+		w := csv.NewWriter(out)
+		w.Write([]string{"id", "url", "game_id", "move", "turn", "rating", "rating_deviation", "popularity", "plays", "themes"})
+		for _, puzzle := range puzzles {
+			w.Write([]string{
+				puzzle.ID.String(),
+				puzzle.URL(),
+				puzzle.GameID.String(),
+				strconv.Itoa(int(puzzle.Move)),
+				puzzle.Turn.Name(),
+				strconv.Itoa(int(puzzle.Rating)),
+				strconv.Itoa(int(puzzle.RatingDeviation)),
+				strconv.Itoa(int(puzzle.Popularity)),
+				strconv.Itoa(int(puzzle.Plays)),
+				puzzle.Themes.String(),
+			})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return fmt.Errorf("failed to write puzzles: %w", err)
+		}
+	case JSONExport:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(puzzles); err != nil {
+			return fmt.Errorf("failed to write puzzles: %w", err)
+		}
+	case PGNExport, StudyExport:
+		w := bufio.NewWriter(out)
+		for _, puzzle := range puzzles {
+			solution, ok := s.Solutions[puzzle.ID]
+			if !ok {
+				continue
+			}
+			var err error
+			if format == PGNExport {
+				err = s.writePuzzlePGN(w, puzzle, solution)
+			} else {
+				err = s.writeStudyChapter(w, puzzle, solution)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to write puzzle %s: %w", puzzle.ID, err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("failed to write puzzles: %w", err)
+		}
+	default:
+		panic("unreachable")
+	}
+	return nil
+}
+
+func (s *Index) writePuzzlePGN(w *bufio.Writer, puzzle PuzzleData, solution PuzzleSolution) error {
+	tags := [][2]string{
+		{"Event", "Puzzle " + puzzle.ID.String()},
+		{"Site", puzzle.GameURL()},
+		{"Date", "????.??.??"},
+		{"Round", "-"},
+		{"White", "?"},
+		{"Black", "?"},
+		{"Result", "*"},
+		{"FEN", solution.FEN},
+		{"SetUp", "1"},
+	}
+	if opening := s.PuzzleOpening(puzzle.ID); !opening.Empty() {
+		tags = append(tags, [2]string{"Opening", opening.String()})
+	}
+	tags = append(tags,
+		[2]string{"PuzzleURL", puzzle.URL()},
+		[2]string{"PuzzleRating", strconv.Itoa(int(puzzle.Rating))},
+		[2]string{"PuzzleThemes", puzzle.Themes.String()},
+	)
+	return writePGN(w, tags, solution, "")
+}
+
+// writeStudyChapter names the chapter after the opening and puts
+// the board to the solving side, as lichess reads from the tags
+func (s *Index) writeStudyChapter(w *bufio.Writer, puzzle PuzzleData, solution PuzzleSolution) error {
+	name := puzzle.ID.String()
+	if opening := s.PuzzleOpening(puzzle.ID); !opening.Empty() {
+		name = opening.String() + " · " + name
+	}
+	tags := [][2]string{
+		{"Event", name},
+		{"ChapterName", name},
+		{"Site", puzzle.URL()},
+		{"Result", "*"},
+		{"FEN", solution.FEN},
+		{"SetUp", "1"},
+		{"Orientation", strings.ToLower(puzzle.Turn.Name())},
+	}
+	comment := fmt.Sprintf("Find the best move for %s. Rating %d, themes: %s. Source game: %s",
+		puzzle.Turn.Name(), puzzle.Rating, puzzle.Themes, puzzle.GameURL())
+	return writePGN(w, tags, solution, comment)
+}
+
+// writePGN writes the solution from the puzzle position, the
+// comment goes after the opponent's move, where puzzle starts
+func writePGN(w *bufio.Writer, tags [][2]string, solution PuzzleSolution, comment string) error {
+	game, err := solution.Replay(len(solution.Moves))
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		fmt.Fprintf(w, "[%s \"%s\"]\n", tag[0], pgnTagEscaper.Replace(tag[1]))
+	}
+	w.WriteString("\n")
+	writeMoves(w, game, comment)
+
+	_, err = w.WriteString("*\n\n")
+	return err
+}
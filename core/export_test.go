@@ -0,0 +1,102 @@
+package core
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestExportPuzzles(t *testing.T) {
+	const fen = "6k1/5ppp/8/8/8/8/5PPP/3RR1K1 b - - 0 1"
+
+	index := newTestIndex(t, [3]string{"C50", "Italian Game", italianGame})
+	solved := PuzzleData{
+		Move:       1,
+		Turn:       chess.White,
+		ID:         ParsePuzzleID("00001"),
+		GameID:     ParseGameID("game0001"),
+		Rating:     1500,
+		Popularity: 90,
+		Themes:     ParseThemes("mateIn1 backRankMate"),
+	}
+	unsolved := solved
+	unsolved.ID = ParsePuzzleID("00002")
+	index.Puzzles["Italian_Game"] = []PuzzleData{solved, unsolved}
+
+	solution, err := ParsePuzzleSolution(fen, "g8h8 e1e8")
+	if err != nil {
+		t.Fatalf("invalid test solution: %v", err)
+	}
+	index.Solutions = SolutionsIndex{solved.ID: solution}
+
+	tests := []struct {
+		format ExportFormat
+		want   []string // lines expected in the export
+		skip   string   // puzzle expected to be missing
+	}{
+		{URLsExport, []string{solved.URL(), unsolved.URL()}, ""},
+		{JSONExport, []string{`"id": "00001",`, `"id": "00002",`}, ""},
+		{PGNExport, []string{
+			`[Event "Puzzle 00001"]`,
+			`[Site "https://lichess.org/game0001#2"]`,
+			`[FEN "` + fen + `"]`,
+			`[Opening "Italian Game"]`,
+			`[PuzzleRating "1500"]`,
+			`1... Kh8 2. Re8# *`,
+		}, "00002"},
+		{StudyExport, []string{
+			`[ChapterName "Italian Game · 00001"]`,
+			`[Site "https://lichess.org/training/00001"]`,
+			`[Orientation "white"]`,
+			`1... Kh8 { Find the best move for White. Rating 1500, themes: ` + solved.Themes.String() +
+				`. Source game: https://lichess.org/game0001#2 } 2. Re8# *`,
+		}, "00002"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format.String(), func(t *testing.T) {
+			var out strings.Builder
+			if err := index.ExportPuzzles(&out, test.format, []PuzzleData{solved, unsolved}); err != nil {
+				t.Fatalf("ExportPuzzles() error = %v", err)
+			}
+			lines := strings.Split(out.String(), "\n")
+			for _, want := range test.want {
+				if !containsLine(lines, want) {
+					t.Errorf("missing line %q in:\n%s", want, out.String())
+				}
+			}
+			if len(test.skip) > 0 && strings.Contains(out.String(), test.skip) {
+				t.Errorf("puzzle %s without solution exported:\n%s", test.skip, out.String())
+			}
+		})
+	}
+
+	t.Run(CSVExport.String(), func(t *testing.T) {
+		var out strings.Builder
+		if err := index.ExportPuzzles(&out, CSVExport, []PuzzleData{solved, unsolved}); err != nil {
+			t.Fatalf("ExportPuzzles() error = %v", err)
+		}
+		records, err := csv.NewReader(strings.NewReader(out.String())).ReadAll()
+		if err != nil {
+			t.Fatalf("invalid csv: %v", err)
+		}
+		if len(records) != 3 {
+			t.Fatalf("%d csv records, want header and 2 puzzles", len(records))
+		}
+		want := []string{"00001", solved.URL(), "game0001", "1", "White", "1500", "0", "90", "0", solved.Themes.String()}
+		if strings.Join(records[1], ",") != strings.Join(want, ",") {
+			t.Errorf("csv record = %v, want %v", records[1], want)
+		}
+	})
+}
+
+func containsLine(lines []string, line string) bool {
+	for _, l := range lines {
+		if strings.TrimSpace(l) == line {
+			return true
+		}
+	}
+	return false
+}
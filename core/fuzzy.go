@@ -0,0 +1,145 @@
+package core
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// transliterations make umlauts spelled with e match
+// the letter without diacritics, e.g. Gruenfeld = Grünfeld
+var transliterations = strings.NewReplacer("ue", "u", "oe", "o", "ae", "a")
+
+// normalizeName splits the name into lowercase words without
+// diacritics and punctuation, so user input matches database names
+func normalizeName(s string) []string {
+	s = strings.ToLower(RemoveDiacritics(s))
+	s = transliterations.Replace(s)
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SearchOpeningNames finds the openings tree nodes which names match every
+// query word, allowing typos; the best matches go first, families before
+// their variations, nodes without a line to play are skipped
+func (s *Index) SearchOpeningNames(query string, limit int) []*OpeningNode {
+	words := normalizeName(query)
+	if len(words) == 0 {
+		return nil
+	}
+
+	type match struct {
+		node  *OpeningNode
+		score int
+	}
+
+	var matches []match
+	var walk func(node *OpeningNode)
+	walk = func(node *OpeningNode) {
+		for _, child := range node.Children {
+			if child.Opening != nil {
+				if score, ok := matchName(words, normalizeName(strings.Join(child.Path, " "))); ok {
+					matches = append(matches, match{node: child, score: score})
+				}
+			}
+			walk(child)
+		}
+	}
+	walk(s.OpeningTree())
+
+	slices.SortFunc(matches, func(a, b match) int {
+		return cmp.Or(
+			cmp.Compare(a.score, b.score),
+			cmp.Compare(len(a.node.Path), len(b.node.Path)),
+			strings.Compare(a.node.Opening.Name.Name, b.node.Opening.Name.Name),
+		)
+	})
+
+	nodes := make([]*OpeningNode, 0, min(len(matches), limit))
+	for _, m := range matches[:min(len(matches), limit)] {
+		nodes = append(nodes, m.node)
+	}
+	return nodes
+}
+
+// matchName scores how far the query words are from the
+// name words, the lower the better; every word must match
+func matchName(query, name []string) (score int, ok bool) {
+	for _, word := range query {
+		best := -1
+		for _, candidate := range name {
+			if d, ok := matchWord(word, candidate); ok && (best < 0 || d < best) {
+				best = d
+			}
+		}
+		if best < 0 {
+			return 0, false
+		}
+		score += best
+	}
+	return score, true
+}
+
+// matchWord allows the query word to be a prefix of the
+// name word, typos are tolerated in longer words only
+func matchWord(word, candidate string) (distance int, ok bool) {
+	if strings.HasPrefix(candidate, word) {
+		if len(candidate) == len(word) {
+			return 0, true
+		}
+		return 1, true // prefer whole words
+	}
+
+	allowed := typosAllowed(word)
+	if allowed == 0 {
+		return 0, false
+	}
+
+	prefix := candidate[:min(len(candidate), len(word))]
+	distance = min(editDistance(word, candidate), editDistance(word, prefix)+1)
+	if distance > allowed {
+		return 0, false
+	}
+	return distance + 1, true
+}
+
+func typosAllowed(word string) int {
+	switch n := len(word); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance is Damerau-Levenshtein distance
+// with adjacent transpositions, e.g. "najdrof"
+func editDistance(a, b string) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return prev[len(b)]
+}
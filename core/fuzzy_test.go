@@ -0,0 +1,73 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSearchOpeningNames(t *testing.T) {
+	const sicilian = "1. e4 c5 2. Nf3 d6 3. d4 cxd4 4. Nxd4 Nf6 5. Nc3"
+	index := newTestIndex(t,
+		[3]string{"D80", "Grünfeld Defense", "1. d4 Nf6 2. c4 g6 3. Nc3 d5"},
+		[3]string{"B20", "Sicilian Defense", "1. e4 c5"},
+		[3]string{"B90", "Sicilian Defense: Najdorf Variation", sicilian + " a6"},
+		[3]string{"B70", "Sicilian Defense: Dragon Variation", sicilian + " g6"},
+		[3]string{"C50", "Italian Game", italianGame},
+	)
+
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		want  []string
+	}{
+		{"empty query", " ", 10, nil},
+		{"family before variations", "sicilian", 10, []string{
+			"Sicilian Defense",
+			"Sicilian Defense: Dragon Variation",
+			"Sicilian Defense: Najdorf Variation",
+		}},
+		{"limited", "sicilian", 1, []string{"Sicilian Defense"}},
+		{"diacritics", "grunfeld", 10, []string{"Grünfeld Defense"}},
+		{"umlaut spelled with e", "Gruenfeld", 10, []string{"Grünfeld Defense"}},
+		{"word prefixes", "sic naj", 10, []string{"Sicilian Defense: Najdorf Variation"}},
+		{"transposed letters", "najdrof", 10, []string{"Sicilian Defense: Najdorf Variation"}},
+		{"typo", "sicillian dragon", 10, []string{"Sicilian Defense: Dragon Variation"}},
+		{"typo in short word", "itl", 10, nil},
+		{"every word must match", "dragon najdorf", 10, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, node := range index.SearchOpeningNames(test.query, test.limit) {
+				got = append(got, node.Opening.Name.Name)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("SearchOpeningNames(%q) = %v, want %v", test.query, got, test.want)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"najdorf", "najdorf", 0},
+		{"najdrof", "najdorf", 1},
+		{"najdorf", "najdorff", 1},
+		{"najorf", "najdorf", 1},
+		{"nadjrof", "najdorf", 2},
+		{"", "dragon", 6},
+	}
+
+	for _, test := range tests {
+		t.Run(test.a+"/"+test.b, func(t *testing.T) {
+			if got := editDistance(test.a, test.b); got != test.want {
+				t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+			}
+		})
+	}
+}
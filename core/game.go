@@ -2,6 +2,7 @@ package core
 
 import (
 	"fmt"
+	"iter"
 	"regexp"
 	"strings"
 
@@ -89,45 +90,77 @@ func (g Game) Empty() bool {
 	return len(g) == 0
 }
 
-func (g Game) ContainsMoves(moves []*chess.Move) bool {
+// ContainsMoves tells whether the moves are played in a row starting
+// from the ply, or up to tolerance plies before or after it
+func (g Game) ContainsMoves(moves []*chess.Move, ply, tolerance int) bool {
 	if len(moves) == 0 {
 		return true
 	}
 
-	// todo: check move number
-	var j int
-	for i := range g {
-		if g[i] == GameFromChess(moves[j]) {
-			j++
-			if j == len(moves) {
-				return true
-			}
-		} else if j > 0 {
-			return false
+	for start := max(0, ply-tolerance); start <= ply+tolerance && start+len(moves) <= len(g); start++ {
+		if g.playsMoves(start, moves) {
+			return true
 		}
 	}
 
 	return false
 }
 
+func (g Game) playsMoves(start int, moves []*chess.Move) bool {
+	for i, move := range moves {
+		if g[start+i] != GameFromChess(move) {
+			return false
+		}
+	}
+	return true
+}
+
 func (g Game) ContainsPosition(pos *chess.Position) bool {
 	if pos == nil {
 		return true
 	}
+	return g.ReachedPosition(pos, 0, len(g))
+}
 
-	game := chess.NewGame(chess.UseNotation(chess.UCINotation{}))
-	for _, move := range g {
-		if err := game.MoveStr(move.String()); err != nil {
-			return false
+// ReachedPosition tells whether the game reached the position
+// from the first ply up to the last one, last is included
+func (g Game) ReachedPosition(pos *chess.Position, first, last int) bool {
+	hash := PositionFromChess(pos).Hash()
+	for ply, position := range g.positions() {
+		if ply > last {
+			break
 		}
-		if game.Position().Hash() == pos.Hash() {
+		if ply >= first && PositionFromChess(position).Hash() == hash {
 			return true
 		}
 	}
-
 	return false
 }
 
+// positions replays the game yielding the position after each
+// ply, the starting one is skipped; replay stops on illegal move
+func (g Game) positions() iter.Seq2[int, *chess.Position] {
+	return func(yield func(int, *chess.Position) bool) {
+		game := chess.NewGame(chess.UseNotation(chess.UCINotation{}))
+		for ply, move := range g {
+			if err := game.MoveStr(move.String()); err != nil || !yield(ply+1, game.Position()) {
+				return
+			}
+		}
+	}
+}
+
+// Replay plays the first plies of the game
+func (g Game) Replay(plies int) (*chess.Game, error) {
+	game := chess.NewGame(chess.UseNotation(chess.UCINotation{}))
+	for _, move := range g[:min(plies, len(g))] {
+		if err := game.MoveStr(move.String()); err != nil {
+			return nil, fmt.Errorf("failed to replay move %s: %w", move, err)
+		}
+	}
+	return game, nil
+}
+
 type GamesIndex map[GameID]Game
 
 func (i GamesIndex) Insert(id, moves string) error {
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestGameContainsMoves(t *testing.T) {
+	// Nf3 Nc6 is played on plies 2 and 6
+	const pgn = "1. e4 e5 2. Nf3 Nc6 3. Ng1 Nb8 4. Nf3 Nc6 5. Bb5 a6"
+	game := parseTestGame(t, pgn)
+
+	const lateLine = "1. e4 e5 2. Nf3 Nc6 3. Ng1 Nb8 4. Nf3 Nc6"
+	tests := []struct {
+		name      string
+		line      string
+		from      int // moves searched are the line ones from the ply
+		ply       int
+		tolerance int
+		want      bool
+	}{
+		{"no moves", "1. e4 e5", 2, 2, 0, true},
+		{"whole game", pgn, 0, 0, 0, true},
+		{"early match", "1. e4 e5 2. Nf3 Nc6", 2, 2, 0, true},
+		{"late match", lateLine, 6, 6, 0, true},
+		{"moves played on another ply", lateLine, 6, 4, 0, false},
+		{"shift beyond tolerance", lateLine, 6, 4, 1, false},
+		{"shift within tolerance", lateLine, 6, 4, 2, true},
+		{"shift back within tolerance", lateLine, 6, 8, 2, true},
+		{"partial match", "1. e4 e5 2. Nf3 Nc6 3. Bc4", 2, 2, 0, false},
+		{"partial match within tolerance", "1. e4 e5 2. Nf3 Nc6 3. Bc4", 2, 2, 4, false},
+		{"moves past the game end", pgn + " 6. Ba4", 8, 8, 40, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			moves := parseTestMoves(t, test.line)[test.from:]
+			if got := game.ContainsMoves(moves, test.ply, test.tolerance); got != test.want {
+				t.Errorf("ContainsMoves(ply %d, tolerance %d) = %v, want %v", test.ply, test.tolerance, got, test.want)
+			}
+		})
+	}
+}
+
+func parseTestGame(t *testing.T, pgn string) Game {
+	t.Helper()
+	var game Game
+	for _, move := range parseTestMoves(t, pgn) {
+		game = append(game, GameFromChess(move))
+	}
+	return game
+}
+
+func parseTestMoves(t *testing.T, pgn string) []*chess.Move {
+	t.Helper()
+	game, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatalf("invalid test moves %q: %v", pgn, err)
+	}
+	return game.Moves()
+}
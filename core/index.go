@@ -1,9 +1,16 @@
 package core
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
 	"log/slog"
+	"math"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,6 +23,10 @@ type SearchType int8
 const (
 	MoveSequenceSearch SearchType = iota
 	PositionSearch
+	TranspositionSearch // position reached by any move order, whatever the opening tag
+	PatternSearch       // games matching the move pattern
+	StructureSearch     // positions with the same pawn skeleton
+	BoardSearch         // positions matching the partial board
 )
 
 func (t SearchType) String() string {
@@ -24,11 +35,39 @@ func (t SearchType) String() string {
 		return "By moves"
 	case PositionSearch:
 		return "By position"
+	case TranspositionSearch:
+		return "By transposition"
+	case PatternSearch:
+		return "By pattern"
+	case StructureSearch:
+		return "By structure"
+	case BoardSearch:
+		return "By board pattern"
 	default:
 		panic("unreachable")
 	}
 }
 
+func ParseSearchType(s string) (t SearchType, err error) {
+	switch strings.ToLower(s) {
+	case "moves":
+		t = MoveSequenceSearch
+	case "position":
+		t = PositionSearch
+	case "transposition":
+		t = TranspositionSearch
+	case "pattern":
+		t = PatternSearch
+	case "structure":
+		t = StructureSearch
+	case "board":
+		t = BoardSearch
+	default:
+		err = fmt.Errorf("invalid search type: %s", s)
+	}
+	return
+}
+
 type Turn int8
 
 const (
@@ -50,6 +89,31 @@ func (t Turn) String() string {
 	}
 }
 
+func ParseTurn(s string) (t Turn, err error) {
+	switch strings.ToLower(s) {
+	case "white", "w":
+		t = WhiteTurn
+	case "black", "b":
+		t = BlackTurn
+	case "either", "":
+		t = EitherTurn
+	default:
+		err = fmt.Errorf("invalid turn: %s", s)
+	}
+	return
+}
+
+func TurnFromChess(c chess.Color) Turn {
+	switch c {
+	case chess.White:
+		return WhiteTurn
+	case chess.Black:
+		return BlackTurn
+	default:
+		return EitherTurn
+	}
+}
+
 func (t Turn) ToChess() chess.Color {
 	switch t {
 	case WhiteTurn:
@@ -63,15 +127,31 @@ func (t Turn) ToChess() chess.Color {
 	}
 }
 
+// IndexVersion must be increased on every change
+// of the indexes binary format, so outdated index
+// files are rejected on load instead of misread
+const IndexVersion uint32 = 5
+
 type Index struct {
-	Openings OpeningsIndex
-	Games    GamesIndex
-	Puzzles  PuzzlesIndex
+	Openings   OpeningsIndex
+	Games      GamesIndex
+	Positions  PositionsIndex
+	Structures StructuresIndex
+	Puzzles    PuzzlesIndex
+	Solutions  SolutionsIndex
+
+	puzzlesOnce    sync.Once
+	puzzles        map[PuzzleID]PuzzleData
+	puzzleOpenings map[PuzzleID]OpeningName
+	gamePuzzles    map[GameID][]PuzzleID
+
+	openingTreeOnce sync.Once
+	openingTree     *OpeningNode
 }
 
 func LoadIndex() (*Index, error) {
 	start := time.Now()
-	openings, err := resources.LoadIndex[OpeningsIndex](filepath.Join("indexes", "openings.index"))
+	openings, err := resources.LoadIndex[OpeningsIndex](filepath.Join("indexes", "openings.index"), IndexVersion)
 	if err != nil {
 		slog.Warn("failed to load openings index")
 		return nil, err
@@ -79,7 +159,7 @@ func LoadIndex() (*Index, error) {
 	slog.Info("loaded openings index", "size", len(openings), "took", time.Since(start))
 
 	start = time.Now()
-	games, err := resources.LoadIndex[GamesIndex](filepath.Join("indexes", "games.index"))
+	games, err := resources.LoadIndex[GamesIndex](filepath.Join("indexes", "games.index"), IndexVersion)
 	if err != nil {
 		slog.Warn("failed to load games index")
 		return nil, err
@@ -87,17 +167,44 @@ func LoadIndex() (*Index, error) {
 	slog.Info("loaded games index", "size", len(games), "took", time.Since(start))
 
 	start = time.Now()
-	puzzles, err := resources.LoadIndex[PuzzlesIndex](filepath.Join("indexes", "puzzles.index"))
+	positions, err := resources.LoadIndex[PositionsIndex](filepath.Join("indexes", "positions.index"), IndexVersion)
+	if err != nil {
+		slog.Warn("failed to load positions index, games are replayed instead", "err", err)
+	} else {
+		slog.Info("loaded positions index", "size", len(positions), "took", time.Since(start))
+	}
+
+	start = time.Now()
+	structures, err := resources.LoadIndex[StructuresIndex](filepath.Join("indexes", "structures.index"), IndexVersion)
+	if err != nil {
+		slog.Warn("failed to load structures index, games are replayed instead", "err", err)
+	} else {
+		slog.Info("loaded structures index", "size", len(structures), "took", time.Since(start))
+	}
+
+	start = time.Now()
+	puzzles, err := resources.LoadIndex[PuzzlesIndex](filepath.Join("indexes", "puzzles.index"), IndexVersion)
 	if err != nil {
 		slog.Warn("failed to load puzzles index")
 		return nil, err
 	}
 	slog.Info("loaded puzzles index", "size", len(puzzles), "took", time.Since(start))
 
+	start = time.Now()
+	solutions, err := resources.LoadIndex[SolutionsIndex](filepath.Join("indexes", "solutions.index"), IndexVersion)
+	if err != nil {
+		slog.Warn("failed to load solutions index, puzzles can not be solved", "err", err)
+	} else {
+		slog.Info("loaded solutions index", "size", len(solutions), "took", time.Since(start))
+	}
+
 	return &Index{
-		Openings: openings,
-		Games:    games,
-		Puzzles:  puzzles,
+		Openings:   openings,
+		Games:      games,
+		Positions:  positions,
+		Structures: structures,
+		Puzzles:    puzzles,
+		Solutions:  solutions,
 	}, nil
 }
 
@@ -105,8 +212,8 @@ func (s *Index) SearchOpening(game *chess.Game) (found OpeningName, leftover []*
 	positions := game.Positions()
 	for i := len(positions) - 1; i > 0; i-- {
 		pos := PositionFromChess(positions[i]).Hash()
-		if name, ok := s.Openings[pos]; ok {
-			found = name
+		if opening, ok := s.Openings[pos]; ok {
+			found = opening.Name
 			leftover = game.Moves()[i:]
 			return
 		}
@@ -114,9 +221,101 @@ func (s *Index) SearchOpening(game *chess.Game) (found OpeningName, leftover []*
 	return
 }
 
-type finding struct {
-	puzzle PuzzleData
-	game   Game
+// Transposition returns the book line of the game opening if the game
+// reached the opening position by another move order; games set up
+// from a position have no move order to compare with the book one
+func (s *Index) Transposition(game *chess.Game) (book Opening, ok bool) {
+	positions := game.Positions()
+	if positions[0].String() != startingFEN {
+		return
+	}
+	for i := len(positions) - 1; i > 0; i-- {
+		opening, found := s.Openings[PositionFromChess(positions[i]).Hash()]
+		if !found {
+			continue
+		}
+		if len(opening.Moves) != i {
+			return opening, true
+		}
+		for j, move := range game.Moves()[:i] {
+			if opening.Moves[j] != GameFromChess(move) {
+				return opening, true
+			}
+		}
+		return
+	}
+	return
+}
+
+// Query describes puzzle search, the position is either
+// reached by the game moves or set up directly (e.g. by FEN);
+// if opening tags, family or ECO range are set instead,
+// puzzles of all the matching openings are searched
+type Query struct {
+	Game     *chess.Game
+	Position *chess.Position
+	Tags     []string // lichess opening tags, e.g. Sicilian_Defense_Najdorf_Variation
+	Family   string
+	ECO      ECORange
+	Pattern  MovePattern  // for the pattern search
+	Material bool         // structure search requires the same material too
+	Board    BoardPattern // for the board pattern search
+	Strategy SearchType
+	// Tolerance is how many plies the moves searched may
+	// be played earlier or later than right after the opening
+	Tolerance uint8
+	PuzzleFilter
+	Sort SortOrder
+}
+
+var (
+	ErrNoPosition   = errors.New("neither moves nor position to search from")
+	ErrEmptyPattern = errors.New("empty pattern to search")
+)
+
+// startingHash is the hash of the position every game passes through
+var startingHash = PositionFromChess(chess.StartingPosition()).Hash()
+
+// Validate tells whether the query has what its search starts from, the game
+// or the position unless openings, pattern or board are searched; empty ones
+// are rejected, as they match every game and the search scans the whole index
+func (q Query) Validate() error {
+	switch {
+	case q.byOpenings():
+	case q.Strategy == PatternSearch:
+		if q.Pattern.Empty() {
+			return ErrEmptyPattern
+		}
+	case q.Strategy == BoardSearch:
+		if q.Board.Empty() {
+			return ErrEmptyPattern
+		}
+	default:
+		if position := q.position(); position == nil || PositionFromChess(position).Hash() == startingHash {
+			return ErrNoPosition
+		}
+	}
+	return nil
+}
+
+// position returns the position searched from,
+// set up directly or reached by the game moves
+func (q Query) position() *chess.Position {
+	if q.Position != nil || q.Game == nil {
+		return q.Position
+	}
+	return q.Game.Position()
+}
+
+// setUp tells whether the position searched from is set up instead of
+// reached by moves from the starting one, then its ply is unknown, as
+// the move number of the set up position is typed by hand
+func (q Query) setUp() bool {
+	return q.Position != nil || q.Game.Positions()[0].String() != startingFEN
+}
+
+func (q Query) byOpenings() bool {
+	return len(q.Tags) > 0 || len(q.Family) > 0 || !q.ECO.Empty()
 }
 
 func (s *Index) SearchPuzzles(
@@ -125,68 +324,491 @@ func (s *Index) SearchPuzzles(
 	turn chess.Color,
 	maxMoves uint8,
 ) []PuzzleData {
-	opening, moves := s.SearchOpening(chessGame)
+	results, _ := s.CollectPuzzles(context.Background(), Query{
+		Game:     chessGame,
+		Strategy: strategy,
+		PuzzleFilter: PuzzleFilter{
+			Turn:     turn,
+			MaxMoves: maxMoves,
+		},
+	})
+	return results
+}
+
+// SearchPuzzlesByPosition finds puzzles which source games passed through
+// the position, e.g. set up by FEN; if the position is out of the openings
+// book, puzzles of all the openings are checked
+func (s *Index) SearchPuzzlesByPosition(position *chess.Position, turn chess.Color, maxMoves uint8) []PuzzleData {
+	results, _ := s.CollectPuzzles(context.Background(), Query{
+		Position: position,
+		Strategy: PositionSearch,
+		PuzzleFilter: PuzzleFilter{
+			Turn:     turn,
+			MaxMoves: maxMoves,
+		},
+	})
+	return results
+}
+
+// CollectPuzzles waits for all the search results and sorts them
+func (s *Index) CollectPuzzles(ctx context.Context, q Query) ([]PuzzleData, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	results := slices.Collect(s.SearchPuzzlesCtx(ctx, q))
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("search aborted: %w", err)
+	}
+	SortPuzzles(results, q.Sort)
+	return results, nil
+}
+
+// SearchPuzzlesCtx yields puzzles as soon as they are found, search
+// stops when the context is done or the consumer breaks the loop,
+// so check ctx.Err() to tell aborted search from the finished one;
+// results are not sorted, as they are streamed; invalid query
+// yields nothing, see Query.Validate
+func (s *Index) SearchPuzzlesCtx(ctx context.Context, q Query) iter.Seq[PuzzleData] {
+	if q.Validate() != nil {
+		return func(func(PuzzleData) bool) {}
+	}
+
+	if q.byOpenings() {
+		return s.searchByOpenings(ctx, q)
+	}
+
+	if q.Strategy == PatternSearch {
+		return s.searchByPattern(ctx, q)
+	}
+
+	if q.Strategy == BoardSearch {
+		return s.searchByBoard(ctx, q)
+	}
+
+	if q.setUp() {
+		return s.searchSetUp(ctx, q)
+	}
+
+	halfMoveNum := len(q.Game.Moves())
+	switch q.Strategy {
+	case StructureSearch:
+		return s.searchStructure(ctx, q.Game.Position(), halfMoveNum, q.Material, q.PuzzleFilter)
+	case TranspositionSearch:
+		return s.searchTransposed(ctx, q.Game.Position(), halfMoveNum, q.PuzzleFilter)
+	}
+
+	opening, moves := s.SearchOpening(q.Game)
 	if opening.Empty() {
-		return nil
+		if q.Strategy == PositionSearch {
+			// out of the openings book, puzzles of all the openings are checked
+			filter := q.PuzzleFilter
+			filter.MaxMoves = movesFrom(q.MaxMoves, halfMoveNum)
+			puzzles := s.Puzzles.FilterAll(filter)
+			return s.searchPosition(ctx, puzzles, q.Game.Position(), halfMoveNum)
+		}
+		return func(func(PuzzleData) bool) {}
+	}
+
+	// fast path, sub-variations share
+	// the puzzle tag with their variation
+	if len(moves) == 0 && len(opening.Variations()) <= 1 {
+		return filterPuzzles(ctx, s.Puzzles.Filter(opening.Tag(), q.PuzzleFilter), func(puzzle PuzzleData) bool {
+			_, ok := s.Games[puzzle.GameID]
+			return ok
+		})
+	}
+
+	filter := q.PuzzleFilter
+	filter.MaxMoves = movesFrom(q.MaxMoves, halfMoveNum)
+	puzzles := s.Puzzles.Filter(opening.Tag(), filter)
+
+	switch {
+	case len(moves) == 0:
+		return s.searchPosition(ctx, puzzles, q.Game.Position(), halfMoveNum)
+	case q.Strategy == MoveSequenceSearch:
+		ply := halfMoveNum - len(moves) // the opening position
+		return s.matchPuzzles(ctx, puzzles, func(game Game) bool {
+			return game.ContainsMoves(moves, ply, int(q.Tolerance))
+		})
+	case q.Strategy == PositionSearch:
+		return s.searchPosition(ctx, puzzles, q.Game.Position(), halfMoveNum)
+	default:
+		panic("unreachable")
+	}
+}
+
+// searchByOpenings yields puzzles of the union of the tags, the family
+// and the ECO range openings, puzzle tagged with several of them is
+// yielded once; puzzles are tagged down to the first variation, so
+// the deeper ones are matched by the source games positions; lichess
+// tags the family on every puzzle of its variations, so puzzles of
+// the ECO range tags are checked by their most specific opening
+func (s *Index) searchByOpenings(ctx context.Context, q Query) iter.Seq[PuzzleData] {
+	tags := slices.Clone(q.Tags)
+	if len(q.Family) > 0 {
+		tags = append(tags, s.FamilyTags(q.Family)...)
+	}
+
+	var ecoTags []string
+	var deeper []Opening
+	if !q.ECO.Empty() {
+		for _, opening := range s.Openings {
+			switch {
+			case !q.ECO.Contains(opening.Name.ECO):
+			case len(opening.Name.Variations()) <= 1:
+				ecoTags = append(ecoTags, opening.Name.Tag())
+			default:
+				deeper = append(deeper, opening)
+			}
+		}
+	}
+
+	return func(yield func(PuzzleData) bool) {
+		seen := make(map[PuzzleID]struct{})
+		unique := func(puzzles iter.Seq[PuzzleData]) bool {
+			for puzzle := range puzzles {
+				if ctx.Err() != nil {
+					return false
+				}
+				if _, ok := seen[puzzle.ID]; ok {
+					continue
+				}
+				seen[puzzle.ID] = struct{}{}
+				if !yield(puzzle) {
+					return false
+				}
+			}
+			return true
+		}
+
+		if !unique(s.Puzzles.FilterTags(tags, q.PuzzleFilter)) {
+			return
+		}
+
+		inRange := filterPuzzles(ctx, s.Puzzles.FilterTags(ecoTags, q.PuzzleFilter), func(puzzle PuzzleData) bool {
+			return q.ECO.Contains(s.PuzzleOpening(puzzle.ID).ECO)
+		})
+		if !unique(inRange) {
+			return
+		}
+
+		for _, opening := range deeper {
+			if slices.Contains(tags, opening.Name.Tag()) {
+				continue // all the variation puzzles are yielded
+			}
+			game, err := opening.Game()
+			if err != nil {
+				continue
+			}
+			filter := q.PuzzleFilter
+			filter.MaxMoves = movesFrom(q.MaxMoves, len(opening.Moves))
+			puzzles := s.searchPosition(ctx, s.Puzzles.Filter(opening.Name.Tag(), filter), game.Position(), len(opening.Moves))
+			if !unique(puzzles) {
+				return
+			}
+		}
 	}
+}
 
-	// fast path
-	if len(moves) == 0 {
-		results := make([]PuzzleData, 0, 1000)
-		for puzzle := range s.Puzzles.Filter(opening.Tag(), turn, maxMoves) {
-			if _, ok := s.Games[puzzle.GameID]; ok {
-				results = append(results, puzzle)
+// FamilyTags returns the tags of the family and all its variations,
+// lichess tags puzzles with the family too, but the variations are
+// listed in case the puzzle misses the family tag
+func (s *Index) FamilyTags(family string) []string {
+	family = sanitizeOpeningName(strings.TrimSpace(family))
+	tags := []string{family}
+	for _, opening := range s.Openings {
+		if opening.Name.FamilyTag() == family {
+			if tag := opening.Name.Tag(); !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
 			}
 		}
-		return results
+	}
+	return tags
+}
+
+// movesFrom counts the moves limit from the ply instead of the game
+// start, the limit saturates as it's a byte and the ply is unbounded
+func movesFrom(maxMoves uint8, ply int) uint8 {
+	return uint8(min(int(maxMoves)+ply/2, math.MaxUint8))
+}
+
+// searchSetUp yields puzzles which source games reached the set up position,
+// or its structure, before the puzzle; the position ply is unknown, so the
+// moves limit counts from the ply each game reached it at; games are looked
+// up in the indexes first, but the positions one stops at its depth, so all
+// the games are replayed if it has none of them
+func (s *Index) searchSetUp(ctx context.Context, q Query) iter.Seq[PuzzleData] {
+	position := q.position()
+	filter := q.PuzzleFilter
+	filter.MaxMoves = math.MaxUint8
+	plies := int(q.MaxMoves) * 2
+
+	if q.Strategy == StructureSearch {
+		signature := StructureSignature(position, q.Material)
+		puzzles := s.Puzzles.FilterAll(filter)
+		if len(s.Structures) > 0 {
+			puzzles = s.gamesPuzzles(ctx, s.Structures[signature], filter)
+		}
+		return s.matchPuzzleGames(ctx, puzzles, func(puzzle PuzzleData, game Game) bool {
+			return game.ReachedStructure(signature, q.Material, puzzle.Ply()-plies, puzzle.Ply())
+		})
 	}
 
-	// slow path
-	var wg sync.WaitGroup
-	findingsCh := make(chan finding)
-	puzzlesCh := make(chan PuzzleData)
-	go func() {
-		halfMoveNum := len(chessGame.Moves())
-		maxMoves += uint8(halfMoveNum / 2) // offset from search position
+	hash := PositionFromChess(position).Hash()
+	puzzles := s.Puzzles.FilterAll(filter)
+	if opening, ok := s.Openings[hash]; ok && q.Strategy != TranspositionSearch {
+		puzzles = s.Puzzles.Filter(opening.Name.Tag(), filter)
+	}
+	if len(s.Positions[hash]) > 0 {
+		puzzles = filterPuzzles(ctx, puzzles, func(puzzle PuzzleData) bool {
+			return s.Positions.Contains(hash, puzzle.GameID)
+		})
+	}
+
+	return s.matchPuzzleGames(ctx, puzzles, func(puzzle PuzzleData, game Game) bool {
+		return game.ReachedPosition(position, puzzle.Ply()-plies, puzzle.Ply())
+	})
+}
+
+// searchTransposed yields puzzles which source games passed through the
+// position by any move order, puzzles of all the openings are checked,
+// as games reaching the position by transposition are tagged differently
+func (s *Index) searchTransposed(ctx context.Context, position *chess.Position, halfMoveNum int, filter PuzzleFilter) iter.Seq[PuzzleData] {
+	filter.MaxMoves = movesFrom(filter.MaxMoves, halfMoveNum)
+
+	if halfMoveNum > PositionsIndexDepth || len(s.Positions) == 0 {
+		return s.matchPuzzles(ctx, s.Puzzles.FilterAll(filter), func(game Game) bool {
+			return game.ContainsPosition(position)
+		})
+	}
 
-		for puzzle := range s.Puzzles.Filter(opening.Tag(), turn, maxMoves) {
-			if game, ok := s.Games[puzzle.GameID]; ok {
-				findingsCh <- finding{
-					puzzle: puzzle,
-					game:   game,
+	return s.gamesPuzzles(ctx, s.Positions[PositionFromChess(position).Hash()], filter)
+}
+
+// searchStructure yields puzzles which source games reached the pawn
+// structure of the position, looked up by the structure signature
+func (s *Index) searchStructure(ctx context.Context, position *chess.Position, halfMoveNum int, material bool, filter PuzzleFilter) iter.Seq[PuzzleData] {
+	filter.MaxMoves = movesFrom(filter.MaxMoves, halfMoveNum)
+	signature := StructureSignature(position, material)
+
+	if len(s.Structures) == 0 {
+		return s.matchPuzzles(ctx, s.Puzzles.FilterAll(filter), func(game Game) bool {
+			return game.ReachedStructure(signature, material, 0, len(game))
+		})
+	}
+
+	return s.gamesPuzzles(ctx, s.Structures[signature], filter)
+}
+
+// searchByPattern matches puzzle source games with the pattern, if it
+// starts with exact moves, only games passing through the position they
+// lead to are checked; the moves limit counts from the pattern end, which
+// differs between games for the pattern matched anywhere in the game
+func (s *Index) searchByPattern(ctx context.Context, q Query) iter.Seq[PuzzleData] {
+	filter := q.PuzzleFilter
+	end, ok := q.Pattern.End()
+	if !ok {
+		filter.MaxMoves = math.MaxUint8
+		plies := int(q.MaxMoves) * 2
+		return s.matchPuzzleGames(ctx, s.Puzzles.FilterAll(filter), func(puzzle PuzzleData, game Game) bool {
+			return q.Pattern.MatchEnding(game, puzzle.Ply()-plies, puzzle.Ply())
+		})
+	}
+	filter.MaxMoves = movesFrom(filter.MaxMoves, end)
+
+	puzzles := s.Puzzles.FilterAll(filter)
+	if prefix, ok := q.Pattern.Prefix(); ok && len(prefix.Moves()) <= PositionsIndexDepth && len(s.Positions) > 0 {
+		puzzles = s.gamesPuzzles(ctx, s.Positions[PositionFromChess(prefix.Position()).Hash()], filter)
+	}
+
+	return s.matchPuzzles(ctx, puzzles, q.Pattern.Match)
+}
+
+// searchByBoard yields puzzles which source games reached a board matching
+// the pattern before the puzzle; the moves limit counts from the matching
+// board, which differs between games, so it's checked for every game
+func (s *Index) searchByBoard(ctx context.Context, q Query) iter.Seq[PuzzleData] {
+	filter := q.PuzzleFilter
+	filter.MaxMoves = math.MaxUint8
+	plies := int(q.MaxMoves) * 2
+	return s.matchPuzzleGames(ctx, s.Puzzles.FilterAll(filter), func(puzzle PuzzleData, game Game) bool {
+		return q.Board.Reached(game, puzzle.Ply()-plies, puzzle.Ply())
+	})
+}
+
+// gamesPuzzles yields puzzles of the games
+func (s *Index) gamesPuzzles(ctx context.Context, games []GameID, filter PuzzleFilter) iter.Seq[PuzzleData] {
+	s.puzzlesOnce.Do(s.indexPuzzles)
+	return func(yield func(PuzzleData) bool) {
+		for _, id := range games {
+			if ctx.Err() != nil {
+				return
+			}
+			for _, puzzleID := range s.gamePuzzles[id] {
+				if puzzle := s.puzzles[puzzleID]; filter.Matches(puzzle) && !yield(puzzle) {
+					return
 				}
 			}
 		}
+	}
+}
 
-		close(findingsCh)
-		wg.Wait()
-		close(puzzlesCh)
-	}()
+func (s *Index) searchPosition(
+	ctx context.Context,
+	puzzles iter.Seq[PuzzleData],
+	position *chess.Position,
+	halfMoveNum int,
+) iter.Seq[PuzzleData] {
+	if halfMoveNum > PositionsIndexDepth || len(s.Positions) == 0 {
+		return s.matchPuzzles(ctx, puzzles, func(game Game) bool {
+			return game.ContainsPosition(position)
+		})
+	}
+
+	hash := PositionFromChess(position).Hash()
+	return filterPuzzles(ctx, puzzles, func(puzzle PuzzleData) bool {
+		return s.Positions.Contains(hash, puzzle.GameID)
+	})
+}
 
-	position := chessGame.Position()
-	threads := runtime.NumCPU()
-	for i := 0; i < threads; i++ {
-		wg.Add(1)
+func filterPuzzles(ctx context.Context, puzzles iter.Seq[PuzzleData], matches func(PuzzleData) bool) iter.Seq[PuzzleData] {
+	return func(yield func(PuzzleData) bool) {
+		for puzzle := range puzzles {
+			if ctx.Err() != nil {
+				return
+			}
+			if matches(puzzle) && !yield(puzzle) {
+				return
+			}
+		}
+	}
+}
+
+// PuzzleOpening returns the most specific opening the puzzle is
+// tagged with, the reverse lookup is built on the first call
+func (s *Index) PuzzleOpening(id PuzzleID) OpeningName {
+	s.puzzlesOnce.Do(s.indexPuzzles)
+	return s.puzzleOpenings[id]
+}
+
+// Puzzle looks the puzzle up by id, the lookup
+// is built along with the puzzle openings one
+func (s *Index) Puzzle(id PuzzleID) (puzzle PuzzleData, ok bool) {
+	s.puzzlesOnce.Do(s.indexPuzzles)
+	puzzle, ok = s.puzzles[id]
+	return
+}
+
+func (s *Index) indexPuzzles() {
+	// tags are named after the family and variation main lines,
+	// which are missing in the database for a few variations
+	names := make(map[string]OpeningName, len(s.Openings))
+	for _, opening := range s.Openings {
+		if name := opening.Name; len(name.Variations()) <= 1 {
+			names[name.Tag()] = name
+		}
+	}
+	for _, opening := range s.Openings {
+		name := opening.Name
+		if _, ok := names[name.Tag()]; !ok {
+			names[name.Tag()] = NewOpeningName("", name.Family(), name.Variation())
+		}
+		if _, ok := names[name.FamilyTag()]; !ok {
+			names[name.FamilyTag()] = NewOpeningName("", name.Family())
+		}
+	}
+
+	s.puzzles = make(map[PuzzleID]PuzzleData, len(s.Puzzles))
+	tags := make(map[PuzzleID]string, len(s.Puzzles))
+	for tag, puzzles := range s.Puzzles {
+		for _, puzzle := range puzzles {
+			s.puzzles[puzzle.ID] = puzzle
+			if len(tag) > len(tags[puzzle.ID]) {
+				tags[puzzle.ID] = tag
+			}
+		}
+	}
+
+	s.gamePuzzles = make(map[GameID][]PuzzleID, len(s.puzzles))
+	for id, puzzle := range s.puzzles {
+		s.gamePuzzles[puzzle.GameID] = append(s.gamePuzzles[puzzle.GameID], id)
+	}
+
+	s.puzzleOpenings = make(map[PuzzleID]OpeningName, len(tags))
+	for id, tag := range tags {
+		name, ok := names[tag]
+		if !ok {
+			name = NewOpeningName("", strings.ReplaceAll(tag, "_", " "))
+		}
+		s.puzzleOpenings[id] = name
+	}
+}
+
+type finding struct {
+	puzzle PuzzleData
+	game   Game
+}
+
+// matchPuzzles checks puzzle source games concurrently
+func (s *Index) matchPuzzles(ctx context.Context, puzzles iter.Seq[PuzzleData], matches func(Game) bool) iter.Seq[PuzzleData] {
+	return s.matchPuzzleGames(ctx, puzzles, func(_ PuzzleData, game Game) bool {
+		return matches(game)
+	})
+}
+
+// matchPuzzleGames checks puzzle source games concurrently,
+// the puzzle is passed along, e.g. to check its move only
+func (s *Index) matchPuzzleGames(ctx context.Context, puzzles iter.Seq[PuzzleData], matches func(PuzzleData, Game) bool) iter.Seq[PuzzleData] {
+	return func(yield func(PuzzleData) bool) {
+		// stops the workers if consumer is gone
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		findingsCh := make(chan finding)
+		puzzlesCh := make(chan PuzzleData)
 		go func() {
-			defer wg.Done()
-			for found := range findingsCh {
-				var matches bool
-				switch strategy {
-				case MoveSequenceSearch:
-					matches = found.game.ContainsMoves(moves)
-				case PositionSearch:
-					matches = found.game.ContainsPosition(position)
-				}
-				if matches {
-					puzzlesCh <- found.puzzle
+			defer close(findingsCh)
+			for puzzle := range puzzles {
+				if game, ok := s.Games[puzzle.GameID]; ok {
+					select {
+					case findingsCh <- finding{puzzle: puzzle, game: game}:
+					case <-ctx.Done():
+						return
+					}
 				}
 			}
 		}()
-	}
 
-	results := make([]PuzzleData, 0, 1000)
-	for puzzle := range puzzlesCh {
-		results = append(results, puzzle)
+		var wg sync.WaitGroup
+		threads := runtime.NumCPU()
+		for i := 0; i < threads; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for found := range findingsCh {
+					if ctx.Err() != nil {
+						return
+					}
+					if matches(found.puzzle, found.game) {
+						select {
+						case puzzlesCh <- found.puzzle:
+						case <-ctx.Done():
+							return
+						}
+					}
+				}
+			}()
+		}
+
+		go func() {
+			wg.Wait()
+			close(puzzlesCh)
+		}()
+
+		for puzzle := range puzzlesCh {
+			if !yield(puzzle) {
+				return
+			}
+		}
 	}
-	return results
 }
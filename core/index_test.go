@@ -0,0 +1,423 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+)
+
+const italianGame = "1. e4 e5 2. Nf3 Nc6 3. Bc4"
+
+func TestSearchPuzzlesStreaming(t *testing.T) {
+	const games = 200
+	index := newTestIndex(t, [3]string{"C50", "Italian Game", italianGame})
+	for i := range games {
+		addTestGame(t, index, fmt.Sprintf("game%04d", i), "Italian_Game", italianGame+" Bc5 4. c3 Nf6", 5)
+	}
+
+	// moves after the opening are matched by the concurrent workers
+	query := Query{
+		Game:         parseTestChessGame(t, italianGame+" Bc5"),
+		Strategy:     MoveSequenceSearch,
+		PuzzleFilter: PuzzleFilter{MaxMoves: 10},
+	}
+
+	t.Run("all found", func(t *testing.T) {
+		results, err := index.CollectPuzzles(context.Background(), query)
+		if err != nil || len(results) != games {
+			t.Errorf("found %d puzzles (err %v), want %d", len(results), err, games)
+		}
+	})
+
+	t.Run("consumer breaks", func(t *testing.T) {
+		goroutines := runtime.NumGoroutine()
+		var found int
+		for range index.SearchPuzzlesCtx(context.Background(), query) {
+			if found++; found == 3 {
+				break
+			}
+		}
+		if found != 3 {
+			t.Errorf("found %d puzzles before break, want 3", found)
+		}
+		assertGoroutinesStopped(t, goroutines)
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		goroutines := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var found int
+		for range index.SearchPuzzlesCtx(ctx, query) {
+			found++
+		}
+		if found == games {
+			t.Errorf("found all %d puzzles after cancel", found)
+		}
+		if _, err := index.CollectPuzzles(ctx, query); !errors.Is(err, context.Canceled) {
+			t.Errorf("CollectPuzzles error = %v, want %v", err, context.Canceled)
+		}
+		assertGoroutinesStopped(t, goroutines)
+	})
+
+	t.Run("cancelled while consuming", func(t *testing.T) {
+		goroutines := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var found int
+		for range index.SearchPuzzlesCtx(ctx, query) {
+			if found++; found == 3 {
+				cancel()
+			}
+		}
+		if found == games {
+			t.Errorf("found all %d puzzles after cancel", found)
+		}
+		assertGoroutinesStopped(t, goroutines)
+	})
+}
+
+func TestSearchPuzzlesWithoutPosition(t *testing.T) {
+	index := newTestIndex(t, [3]string{"C50", "Italian Game", italianGame})
+	addTestGame(t, index, "game0001", "Italian_Game", italianGame+" Bc5", 5)
+
+	for _, strategy := range []SearchType{MoveSequenceSearch, PositionSearch, TranspositionSearch, StructureSearch} {
+		t.Run(strategy.String(), func(t *testing.T) {
+			query := Query{Strategy: strategy, PuzzleFilter: PuzzleFilter{MaxMoves: 10}}
+			if _, err := index.CollectPuzzles(context.Background(), query); !errors.Is(err, ErrNoPosition) {
+				t.Errorf("CollectPuzzles error = %v, want %v", err, ErrNoPosition)
+			}
+			for range index.SearchPuzzlesCtx(context.Background(), query) {
+				t.Errorf("puzzle found without position")
+			}
+		})
+	}
+
+	t.Run("starting position", func(t *testing.T) {
+		query := Query{Game: chess.NewGame(), Strategy: PositionSearch, PuzzleFilter: PuzzleFilter{MaxMoves: 10}}
+		if _, err := index.CollectPuzzles(context.Background(), query); !errors.Is(err, ErrNoPosition) {
+			t.Errorf("CollectPuzzles error = %v, want %v", err, ErrNoPosition)
+		}
+	})
+
+	for _, strategy := range []SearchType{PatternSearch, BoardSearch} {
+		t.Run("empty "+strategy.String(), func(t *testing.T) {
+			query := Query{Strategy: strategy, PuzzleFilter: PuzzleFilter{MaxMoves: 10}}
+			if _, err := index.CollectPuzzles(context.Background(), query); !errors.Is(err, ErrEmptyPattern) {
+				t.Errorf("CollectPuzzles error = %v, want %v", err, ErrEmptyPattern)
+			}
+		})
+	}
+}
+
+func TestSearchPuzzlesBySetUpPosition(t *testing.T) {
+	index := newTestIndex(t, [3]string{"C50", "Italian Game", italianGame})
+	addTestGame(t, index, "game0001", "Italian_Game", italianGame+" Bc5 4. c3 Nf6", 5)
+	addTestGame(t, index, "game0002", "Italian_Game", "1. e4 e5 2. Bc4 Nc6 3. Nf3 Nf6", 6)
+
+	// the move number is left at 1, as the editor sets it up by default
+	position := parseTestChessPosition(t, "r1bqkbnr/pppp1ppp/2n5/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 1")
+
+	tests := []struct {
+		name     string
+		strategy SearchType
+		maxMoves uint8
+		want     []string
+	}{
+		{"position", PositionSearch, 4, []string{"e0001", "e0002"}},
+		{"position within max moves", PositionSearch, 3, []string{"e0001"}},
+		{"transposition within max moves", TranspositionSearch, 3, []string{"e0001"}},
+		{"structure within max moves", StructureSearch, 2, []string{"e0001"}},
+		{"out of max moves", PositionSearch, 2, nil},
+	}
+
+	search := func(t *testing.T, query Query, want []string) {
+		t.Helper()
+		results, err := index.CollectPuzzles(context.Background(), query)
+		if err != nil {
+			t.Fatalf("CollectPuzzles() error = %v", err)
+		}
+		var got []string
+		for _, puzzle := range results {
+			got = append(got, puzzle.ID.String())
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Errorf("found %v, want %v", got, want)
+		}
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			search(t, Query{
+				Position:     position,
+				Strategy:     test.strategy,
+				PuzzleFilter: PuzzleFilter{MaxMoves: test.maxMoves},
+			}, test.want)
+		})
+	}
+
+	// optional indexes may be missing, then the games are replayed
+	index.Positions, index.Structures = nil, nil
+
+	for _, test := range tests {
+		t.Run(test.name+" without indexes", func(t *testing.T) {
+			search(t, Query{
+				Position:     position,
+				Strategy:     test.strategy,
+				PuzzleFilter: PuzzleFilter{MaxMoves: test.maxMoves},
+			}, test.want)
+		})
+	}
+
+	for _, strategy := range []SearchType{TranspositionSearch, StructureSearch} {
+		t.Run("moves "+strategy.String()+" without indexes", func(t *testing.T) {
+			search(t, Query{
+				Game:         parseTestChessGame(t, "1. e4 e5 2. Bc4 Nc6 3. Nf3"),
+				Strategy:     strategy,
+				PuzzleFilter: PuzzleFilter{MaxMoves: 4},
+			}, []string{"e0001", "e0002"})
+		})
+	}
+}
+
+func TestMovesFrom(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxMoves uint8
+		ply      int
+		want     uint8
+	}{
+		{"starting position", 10, 0, 10},
+		{"white to move", 10, 8, 14},
+		{"black to move", 10, 9, 14},
+		{"saturated", 250, 20, math.MaxUint8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := movesFrom(test.maxMoves, test.ply); got != test.want {
+				t.Errorf("movesFrom(%d, %d) = %d, want %d", test.maxMoves, test.ply, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSearchPuzzlesByOpenings(t *testing.T) {
+	const najdorf = "1. e4 c5 2. Nf3 d6 3. d4 cxd4 4. Nxd4 Nf6 5. Nc3 a6"
+	index := newTestIndex(t,
+		[3]string{"B20", "Sicilian Defense", "1. e4 c5"},
+		[3]string{"B90", "Sicilian Defense: Najdorf Variation", najdorf},
+		[3]string{"C50", "Italian Game", italianGame},
+	)
+	addTestGame(t, index, "game0001", "Sicilian_Defense", "1. e4 c5 2. Nc3", 5)
+	variation := addTestGame(t, index, "game0002", "Sicilian_Defense_Najdorf_Variation", najdorf, 5)
+	index.Puzzles["Sicilian_Defense"] = append(index.Puzzles["Sicilian_Defense"], variation) // lichess tags the family too
+	addTestGame(t, index, "game0003", "Italian_Game", italianGame, 5)
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{"tags", Query{Tags: []string{"Italian_Game"}}, []string{"e0003"}},
+		{"family", Query{Family: "Sicilian Defense"}, []string{"e0001", "e0002"}},
+		{"eco range", Query{ECO: ECORange{"B90", "B99"}}, []string{"e0002"}},
+		{"wide eco range", Query{ECO: ECORange{"B00", "C99"}}, []string{"e0001", "e0002", "e0003"}},
+		{"narrow family eco range", Query{ECO: ECORange{"B20", "B29"}}, []string{"e0001"}},
+		{"tags and eco range", Query{Tags: []string{"Italian_Game"}, ECO: ECORange{"B90", "B90"}}, []string{"e0002", "e0003"}},
+		{"empty eco range", Query{ECO: ECORange{"A00", "A99"}}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.query.PuzzleFilter = PuzzleFilter{MaxMoves: 40}
+			results, err := index.CollectPuzzles(context.Background(), test.query)
+			if err != nil {
+				t.Fatalf("CollectPuzzles() error = %v", err)
+			}
+			var got []string
+			for _, puzzle := range results {
+				got = append(got, puzzle.ID.String())
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("found %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	tags := index.FamilyTags(" Sicilian Defense ")
+	slices.Sort(tags)
+	if want := []string{"Sicilian_Defense", "Sicilian_Defense_Najdorf_Variation"}; !slices.Equal(tags, want) {
+		t.Errorf("FamilyTags() = %v, want %v", tags, want)
+	}
+}
+
+func TestTransposition(t *testing.T) {
+	index := newTestIndex(t,
+		[3]string{"C20", "King's Pawn Game", "1. e4 e5"},
+		[3]string{"C50", "Italian Game", italianGame},
+	)
+	const transposed = "1. Nf3 Nc6 2. e4 e5 3. Bc4"
+
+	tests := []struct {
+		name string
+		pgn  string
+		want string // transposed book opening
+	}{
+		{"book order", italianGame, ""},
+		{"book order continued", italianGame + " Bc5", ""},
+		{"another order", transposed, "Italian Game"},
+		{"another order continued", transposed + " Bc5", "Italian Game"},
+		{"earlier opening transposed", "1. Nf3 Nc6 2. e4 e5 3. Nc3", ""},
+		{"out of book", "1. a3 a6", ""},
+		{"set up position", `[FEN "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"] 3. Bc4`, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			book, ok := index.Transposition(parseTestChessGame(t, test.pgn))
+			if ok != (len(test.want) > 0) || book.Name.Name != test.want {
+				t.Errorf("Transposition() = %q, %v, want %q", book.Name, ok, test.want)
+			}
+		})
+	}
+
+	t.Run("search", func(t *testing.T) {
+		addTestGame(t, index, "game0001", "Italian_Game", italianGame+" Bc5", 5)
+		addTestGame(t, index, "game0002", "Four_Knights_Game", transposed+" Nf6", 5)
+		addTestGame(t, index, "game0003", "Italian_Game", "1. e4 e5 2. Bc4 Nf6", 5)
+
+		query := Query{
+			Game:         parseTestChessGame(t, italianGame),
+			Strategy:     TranspositionSearch,
+			PuzzleFilter: PuzzleFilter{MaxMoves: 40},
+		}
+		results, err := index.CollectPuzzles(context.Background(), query)
+		if err != nil {
+			t.Fatalf("CollectPuzzles() error = %v", err)
+		}
+		var got []string
+		for _, puzzle := range results {
+			got = append(got, puzzle.ID.String())
+		}
+		slices.Sort(got)
+		if want := []string{"e0001", "e0002"}; !slices.Equal(got, want) {
+			t.Errorf("found %v, want %v", got, want)
+		}
+	})
+}
+
+func TestSearchPuzzlesByPattern(t *testing.T) {
+	const line = "1. e4 e5 2. Bc4 Nc6 3. Bxf7+ Kxf7 4. Qh5+ g6"
+	index := newTestIndex(t, [3]string{"C23", "Bishop's Opening", "1. e4 e5 2. Bc4"})
+	addTestGame(t, index, "game0001", "Bishops_Opening", line, 4)
+	addTestGame(t, index, "game0002", "Bishops_Opening", line, 10)
+
+	tests := []struct {
+		name     string
+		pattern  string
+		maxMoves uint8
+		want     []string
+	}{
+		{"anywhere", "... * Bxf7+", 40, []string{"e0001", "e0002"}},
+		{"anywhere within max moves", "... * Bxf7+", 2, []string{"e0001"}},
+		{"numbered within max moves", "3. Bxf7+", 2, []string{"e0001"}},
+		{"white move first", "... Bxf7+", 40, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pattern, err := ParseMovePattern(test.pattern)
+			if err != nil {
+				t.Fatalf("invalid test pattern %q: %v", test.pattern, err)
+			}
+			query := Query{
+				Pattern:      pattern,
+				Strategy:     PatternSearch,
+				PuzzleFilter: PuzzleFilter{MaxMoves: test.maxMoves},
+			}
+			results, err := index.CollectPuzzles(context.Background(), query)
+			if err != nil {
+				t.Fatalf("CollectPuzzles() error = %v", err)
+			}
+			var got []string
+			for _, puzzle := range results {
+				got = append(got, puzzle.ID.String())
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, test.want) {
+				t.Errorf("found %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// assertGoroutinesStopped waits for the search workers to exit
+func assertGoroutinesStopped(t *testing.T, want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > want {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left running, want %d", runtime.NumGoroutine(), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// newTestIndex indexes the openings given by ECO, name and moves
+func newTestIndex(t *testing.T, openings ...[3]string) *Index {
+	t.Helper()
+	index := &Index{
+		Openings:   make(OpeningsIndex),
+		Games:      make(GamesIndex),
+		Positions:  make(PositionsIndex),
+		Structures: make(StructuresIndex),
+		Puzzles:    make(PuzzlesIndex),
+	}
+	for _, opening := range openings {
+		if err := index.Openings.Insert(opening[0], opening[1], opening[2]); err != nil {
+			t.Fatalf("invalid test opening %v: %v", opening, err)
+		}
+	}
+	return index
+}
+
+// addTestGame indexes the game with a puzzle tagged with the
+// opening tag, the puzzle id is the last letters of the game id
+func addTestGame(t *testing.T, index *Index, id, tag, line string, move uint8) PuzzleData {
+	t.Helper()
+	game := parseTestChessGame(t, line)
+	gameID := ParseGameID(id)
+	index.Games.InsertFromChess(gameID, game)
+	index.Positions.InsertFromChess(gameID, game)
+	index.Positions.Sort()
+	index.Structures.InsertFromChess(gameID, game)
+
+	puzzle := PuzzleData{
+		Move:   move,
+		Turn:   chess.White,
+		ID:     ParsePuzzleID(id[len(id)-5:]),
+		GameID: gameID,
+		Rating: 1500,
+	}
+	index.Puzzles[tag] = append(index.Puzzles[tag], puzzle)
+	return puzzle
+}
+
+func parseTestChessGame(t *testing.T, pgn string) *chess.Game {
+	t.Helper()
+	game, err := ParsePGN(pgn)
+	if err != nil {
+		t.Fatalf("invalid test moves %q: %v", pgn, err)
+	}
+	return game
+}
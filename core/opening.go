@@ -2,6 +2,7 @@ package core
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 	"unicode"
 
@@ -9,52 +10,101 @@ import (
 	"golang.org/x/text/unicode/norm"
 )
 
-type OpeningName [2]string
+// OpeningName is the family followed by comma separated variations,
+// from the broadest to the most specific one, as in the database
+type OpeningName struct {
+	ECO  string
+	Name string
+}
+
+func ParseOpeningName(eco, s string) OpeningName {
+	family, variations, _ := strings.Cut(s, ":")
+	return NewOpeningName(eco, append([]string{family}, strings.Split(variations, ",")...)...)
+}
 
-func ParseOpeningName(s string) (n OpeningName) {
-	parts := strings.Split(s, ":")
-	n[0] = parts[0]
-	if len(parts) > 1 {
-		variation := strings.TrimSpace(parts[1])
-		n[1] = strings.Split(variation, ",")[0]
+// NewOpeningName joins the family and the variations, empty ones are skipped
+func NewOpeningName(eco string, path ...string) (n OpeningName) {
+	n.ECO = strings.TrimSpace(eco)
+
+	var name strings.Builder
+	for _, part := range path {
+		if part = strings.TrimSpace(part); len(part) == 0 {
+			continue
+		}
+		switch {
+		case name.Len() == 0:
+		case !strings.ContainsRune(name.String(), ':'):
+			name.WriteString(": ")
+		default:
+			name.WriteString(", ")
+		}
+		name.WriteString(part)
 	}
+	n.Name = name.String()
+
 	return
 }
 
 func (n OpeningName) Empty() bool {
-	return len(n[0]) == 0 && len(n[1]) == 0
+	return len(n.Name) == 0
 }
 
 func (n OpeningName) Family() string {
-	return n[0]
+	family, _, _ := strings.Cut(n.Name, ":")
+	return family
 }
 
+// Variation returns the first variation, puzzles are tagged with it
 func (n OpeningName) Variation() string {
-	return n[1]
+	if variations := n.Variations(); len(variations) > 0 {
+		return variations[0]
+	}
+	return ""
+}
+
+func (n OpeningName) Variations() []string {
+	_, variations, found := strings.Cut(n.Name, ":")
+	if !found {
+		return nil
+	}
+	list := strings.Split(variations, ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list
+}
+
+// Path returns the family followed by the variations
+func (n OpeningName) Path() []string {
+	return append([]string{n.Family()}, n.Variations()...)
 }
 
 func (n OpeningName) String() string {
-	var s strings.Builder
-	s.WriteString(n[0])
-	if len(n[1]) > 0 {
-		s.WriteString(": ")
-		s.WriteString(n[1])
+	return n.Name
+}
+
+// Title prefixes the name with the ECO code
+func (n OpeningName) Title() string {
+	if len(n.ECO) == 0 {
+		return n.Name
 	}
-	return s.String()
+	return n.ECO + " " + n.Name
 }
 
 func (n OpeningName) FamilyTag() string {
-	return sanitizeOpeningName(n[0])
+	return sanitizeOpeningName(n.Family())
 }
 
 func (n OpeningName) VariationTag() string {
-	return sanitizeOpeningName(n[1])
+	return sanitizeOpeningName(n.Variation())
 }
 
+// Tag returns the lichess puzzle tag, which
+// includes the first variation only
 func (n OpeningName) Tag() string {
 	var tag strings.Builder
 	tag.WriteString(n.FamilyTag())
-	if len(n[1]) > 0 {
+	if variation := n.Variation(); len(variation) > 0 {
 		tag.WriteRune('_')
 		tag.WriteString(n.VariationTag())
 	}
@@ -80,9 +130,19 @@ func RemoveDiacritics(s string) string {
 	return sb.String()
 }
 
-type OpeningsIndex map[[16]byte]OpeningName
+// Opening is a line of the openings database
+type Opening struct {
+	Name  OpeningName
+	Moves Game
+}
+
+func (o Opening) Game() (*chess.Game, error) {
+	return o.Moves.Replay(len(o.Moves))
+}
+
+type OpeningsIndex map[uint64]Opening
 
-func (i OpeningsIndex) Insert(name, moves string) error {
+func (i OpeningsIndex) Insert(eco, name, moves string) error {
 	pgn, err := chess.PGN(strings.NewReader(moves))
 	if err != nil {
 		return fmt.Errorf("failed to parse moves: %w", err)
@@ -93,8 +153,52 @@ func (i OpeningsIndex) Insert(name, moves string) error {
 		return fmt.Errorf("no moves parsed from %q", moves)
 	}
 
+	line := make(Game, len(game.Moves()))
+	for j, move := range game.Moves() {
+		line[j] = GameFromChess(move)
+	}
+
 	position := PositionFromChess(game.Position()).Hash()
-	i[position] = ParseOpeningName(name)
+	i[position] = Opening{
+		Name:  ParseOpeningName(eco, name),
+		Moves: line,
+	}
 
 	return nil
 }
+
+var ecoRe = regexp.MustCompile(`^[A-E][0-9]{2}$`)
+
+// ECORange is an inclusive range of ECO codes, e.g. B90-B99
+type ECORange struct {
+	From string
+	To   string
+}
+
+func ParseECORange(s string) (r ECORange, err error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	from, to, found := strings.Cut(strings.ReplaceAll(s, "–", "-"), "-")
+	if !found {
+		to = from
+	}
+	r = ECORange{From: strings.TrimSpace(from), To: strings.TrimSpace(to)}
+	if !ecoRe.MatchString(r.From) || !ecoRe.MatchString(r.To) || r.From > r.To {
+		err = fmt.Errorf("invalid eco range: %s", s)
+	}
+	return
+}
+
+func (r ECORange) Empty() bool {
+	return len(r.From) == 0
+}
+
+func (r ECORange) Contains(eco string) bool {
+	return r.From <= eco && eco <= r.To
+}
+
+func (r ECORange) String() string {
+	if r.From == r.To {
+		return r.From
+	}
+	return r.From + "-" + r.To
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestParseECORange(t *testing.T) {
+	tests := []struct {
+		s     string
+		want  ECORange
+		valid bool
+	}{
+		{"B90-B99", ECORange{"B90", "B99"}, true},
+		{"b90 - b99", ECORange{"B90", "B99"}, true},
+		{"B90–B99", ECORange{"B90", "B99"}, true},
+		{"C50", ECORange{"C50", "C50"}, true},
+		{"B99-B90", ECORange{}, false},
+		{"F00", ECORange{}, false},
+		{"B9", ECORange{}, false},
+		{"B90-", ECORange{}, false},
+		{"", ECORange{}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			r, err := ParseECORange(test.s)
+			if valid := err == nil; valid != test.valid {
+				t.Fatalf("ParseECORange(%q) error = %v, want valid %v", test.s, err, test.valid)
+			}
+			if test.valid && r != test.want {
+				t.Errorf("ParseECORange(%q) = %v, want %v", test.s, r, test.want)
+			}
+		})
+	}
+}
+
+func TestECORangeContains(t *testing.T) {
+	r := ECORange{"B20", "B99"}
+
+	tests := []struct {
+		eco  string
+		want bool
+	}{
+		{"B20", true},
+		{"B90", true},
+		{"B99", true},
+		{"B19", false},
+		{"C00", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.eco, func(t *testing.T) {
+			if got := r.Contains(test.eco); got != test.want {
+				t.Errorf("%s contains %q = %v, want %v", r, test.eco, got, test.want)
+			}
+		})
+	}
+}
+
+func TestOpeningNameTags(t *testing.T) {
+	tests := []struct {
+		name      string
+		family    string
+		variation string
+		tag       string
+	}{
+		{"Italian Game", "Italian_Game", "", "Italian_Game"},
+		{"Sicilian Defense: Najdorf Variation, English Attack", "Sicilian_Defense", "Najdorf_Variation", "Sicilian_Defense_Najdorf_Variation"},
+		{"Grünfeld Defense: Exchange Variation", "Grunfeld_Defense", "Exchange_Variation", "Grunfeld_Defense_Exchange_Variation"},
+		{"Bishop's Opening", "Bishops_Opening", "", "Bishops_Opening"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name := ParseOpeningName("", test.name)
+			if name.FamilyTag() != test.family || name.VariationTag() != test.variation || name.Tag() != test.tag {
+				t.Errorf("tags = %s, %s, %s, want %s, %s, %s",
+					name.FamilyTag(), name.VariationTag(), name.Tag(), test.family, test.variation, test.tag)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// MovePattern matches games by a sequence of move patterns, separated by
+// spaces and optionally numbered as in PGN; every pattern is a SAN move,
+// * matches any move, so do the piece moves, captures, checks and mates
+// ending with it, e.g. N*, Bx*, *+, x*#; alternatives are separated by |,
+// e.g. "1. d4 (Nf6|d5) 2. c4 x*" or "* Bxf7+"; the numbered pattern is
+// matched from the move number, otherwise it's matched anywhere in the
+// game, leading ... makes it start with a black move, e.g. "... * Bxf7+"
+type MovePattern struct {
+	source string
+	ply    int  // first move ply, -1 if not numbered
+	black  bool // not numbered pattern starts with a black move
+	steps  [][]movePattern
+	prefix []string // the first moves if they are exact
+}
+
+var movePatternRe = regexp.MustCompile(`^([KQRBNP])?([a-h])?([1-8])?(x)?([a-h][1-8]|\*)(?:=([QRBN]))?[+#]?$`)
+
+// ParseMovePattern compiles the pattern, see MovePattern
+func ParseMovePattern(s string) (p MovePattern, err error) {
+	p.source = strings.TrimSpace(s)
+	p.ply = -1
+
+	exact := true
+	for i, token := range strings.Fields(p.source) {
+		if token == "..." {
+			if i > 0 {
+				return p, errors.New("... must start the move pattern")
+			}
+			p.black = true
+			continue
+		}
+		if number := moveNumberRe.FindString(token); len(number) > 0 {
+			if err = p.setPly(number); err != nil {
+				return
+			}
+			if token = token[len(number):]; len(token) == 0 {
+				continue
+			}
+		}
+
+		step, err := parseMoveStep(token)
+		if err != nil {
+			return p, err
+		}
+		p.steps = append(p.steps, step)
+
+		exact = exact && p.ply == 0 && len(step) == 1 && step[0].exact
+		if exact {
+			p.prefix = append(p.prefix, token)
+		}
+	}
+
+	if len(p.steps) == 0 {
+		err = errors.New("empty move pattern")
+	}
+	return
+}
+
+// setPly anchors the pattern by its first move number,
+// the following numbers must be in order
+func (p *MovePattern) setPly(number string) error {
+	num, err := strconv.Atoi(strings.TrimRight(number, "."))
+	if err != nil || num < 1 {
+		return fmt.Errorf("invalid move number %s", number)
+	}
+
+	ply := (num - 1) * 2
+	if strings.HasSuffix(number, "...") {
+		ply++
+	}
+
+	switch {
+	case p.black && len(p.steps) == 0 && ply%2 == 0:
+		return fmt.Errorf("move number %s is not a black move", number)
+	case p.ply < 0 && len(p.steps) == 0:
+		p.ply = ply
+	case p.ply < 0 || p.ply+len(p.steps) != ply:
+		return fmt.Errorf("move number %s is out of order", number)
+	}
+	return nil
+}
+
+func (p MovePattern) Empty() bool {
+	return len(p.steps) == 0
+}
+
+func (p MovePattern) String() string {
+	return p.source
+}
+
+// End returns the ply after the last pattern move, if it's numbered
+func (p MovePattern) End() (ply int, ok bool) {
+	if p.ply < 0 {
+		return 0, false
+	}
+	return p.ply + len(p.steps), true
+}
+
+// Prefix plays the first pattern moves if they are exact
+// ones from the starting position, so the games to match
+// can be looked up by the position they lead to
+func (p MovePattern) Prefix() (*chess.Game, bool) {
+	if len(p.prefix) == 0 {
+		return nil, false
+	}
+	game, err := ParsePGN(strings.Join(p.prefix, " "))
+	if err != nil {
+		return nil, false
+	}
+	return game, true
+}
+
+// Match tells whether the game moves match the pattern
+func (p MovePattern) Match(game Game) bool {
+	return p.MatchEnding(game, 0, len(game))
+}
+
+// MatchEnding tells whether the game moves match the pattern
+// ending from the first ply up to the last one, last is included
+func (p MovePattern) MatchEnding(game Game, first, last int) bool {
+	if len(p.steps) == 0 {
+		return true
+	}
+
+	pieces := game.pieces()
+	if p.ply >= 0 {
+		end := p.ply + len(p.steps)
+		return first <= end && end <= last && p.matchAt(game, pieces, p.ply)
+	}
+	for start := max(first-len(p.steps), 0); start+len(p.steps) <= last; start++ {
+		if p.black && start%2 == 0 {
+			continue // white move, games start from the standard position
+		}
+		if p.matchAt(game, pieces, start) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p MovePattern) matchAt(game Game, pieces []chess.PieceType, start int) bool {
+	if start+len(p.steps) > len(game) {
+		return false
+	}
+	for i, step := range p.steps {
+		if !matchMoveStep(step, game, pieces, start+i) {
+			return false
+		}
+	}
+	return true
+}
+
+// movePattern is a single move constraint,
+// fields left unset match any move
+type movePattern struct {
+	piece   chess.PieceType
+	file    chess.File
+	rank    chess.Rank
+	to      chess.Square
+	promo   chess.PieceType
+	tags    chess.MoveTag
+	mate    bool // there's no move tag for it
+	anyFile bool
+	anyRank bool
+	exact   bool // a SAN move, not a wildcard
+}
+
+func parseMoveStep(token string) (step []movePattern, err error) {
+	token = strings.TrimSuffix(strings.TrimPrefix(token, "("), ")")
+	for _, alt := range strings.Split(token, "|") {
+		pattern, err := parseMovePattern(alt)
+		if err != nil {
+			return nil, err
+		}
+		step = append(step, pattern)
+	}
+	return
+}
+
+func parseMovePattern(s string) (p movePattern, err error) {
+	p.anyFile, p.anyRank, p.to = true, true, chess.NoSquare
+
+	castle, check := strings.CutSuffix(strings.ReplaceAll(s, "0", "O"), "+")
+	if !check {
+		castle, p.mate = strings.CutSuffix(castle, "#")
+	}
+	if check || p.mate {
+		p.tags |= chess.Check
+	}
+	switch castle {
+	case "O-O":
+		p.piece, p.tags, p.exact = chess.King, p.tags|chess.KingSideCastle, true
+		return
+	case "O-O-O":
+		p.piece, p.tags, p.exact = chess.King, p.tags|chess.QueenSideCastle, true
+		return
+	}
+
+	matches := movePatternRe.FindStringSubmatch(s)
+	if matches == nil {
+		err = fmt.Errorf("invalid move pattern %s", s)
+		return
+	}
+	piece, file, rank, capture, to, promo := matches[1], matches[2], matches[3], matches[4], matches[5], matches[6]
+
+	if len(file) > 0 {
+		p.file, p.anyFile = chess.File(file[0]-'a'), false
+	}
+	if len(rank) > 0 {
+		p.rank, p.anyRank = chess.Rank(rank[0]-'1'), false
+	}
+	if len(capture) > 0 {
+		p.tags |= chess.Capture
+	}
+	if to != "*" {
+		p.to = chess.NewSquare(chess.File(to[0]-'a'), chess.Rank(to[1]-'1'))
+	}
+
+	switch {
+	case len(piece) > 0:
+		p.piece = pieceTypes[piece]
+	case p.to != chess.NoSquare || len(file) > 0 || len(rank) > 0:
+		p.piece = chess.Pawn // as in SAN
+	}
+	if len(promo) > 0 {
+		p.promo = pieceTypes[promo]
+	}
+
+	p.exact = p.to != chess.NoSquare && piece != "P"
+	return
+}
+
+var pieceTypes = map[string]chess.PieceType{
+	"K": chess.King,
+	"Q": chess.Queen,
+	"R": chess.Rook,
+	"B": chess.Bishop,
+	"N": chess.Knight,
+	"P": chess.Pawn,
+}
+
+func matchMoveStep(step []movePattern, game Game, pieces []chess.PieceType, ply int) bool {
+	for _, pattern := range step {
+		if pattern.matches(game[ply], pieces[ply]) && (!pattern.mate || game.checkmated(ply)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p movePattern) matches(move Move, piece chess.PieceType) bool {
+	return (p.piece == chess.NoPieceType || p.piece == piece) &&
+		(p.anyFile || p.file == move.From.File()) &&
+		(p.anyRank || p.rank == move.From.Rank()) &&
+		(p.to == chess.NoSquare || p.to == move.To) &&
+		(p.promo == chess.NoPieceType || p.promo == move.Promo) &&
+		move.Tags&p.tags == p.tags
+}
+
+// checkmated tells whether the move of the ply mates,
+// which is the last one then, so the game is replayed once
+func (g Game) checkmated(ply int) bool {
+	if ply != len(g)-1 || g[ply].Tags&chess.Check == 0 {
+		return false
+	}
+	game, err := g.Replay(len(g))
+	return err == nil && game.Method() == chess.Checkmate
+}
+
+// pieces returns the type of the piece moved on each ply
+func (g Game) pieces() []chess.PieceType {
+	pieces := make([]chess.PieceType, len(g))
+	for ply, board := range g.boards() {
+		if ply < len(g) {
+			pieces[ply] = board[g[ply].From].Type()
+		}
+	}
+	return pieces
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestMovePatternMatch(t *testing.T) {
+	const (
+		scholarsMate = "1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7#"
+		bishopCheck  = "1. e4 e5 2. Bc4 Nc6 3. Bxf7+ Kxf7"
+		castled      = "1. e4 e5 2. Nf3 Nc6 3. Bc4 Bc5 4. O-O"
+		promoted     = "1. e4 d5 2. exd5 c6 3. dxc6 Nf6 4. cxb7 Nbd7 5. bxa8=Q"
+	)
+
+	tests := []struct {
+		name    string
+		pattern string
+		game    string
+		want    bool
+	}{
+		{"exact moves", "e4 e5 Bc4", scholarsMate, true},
+		{"any move", "e4 * Bc4", scholarsMate, true},
+		{"any piece move", "Bc4 N* Q*", scholarsMate, true},
+		{"another piece", "Bc4 B*", scholarsMate, false},
+		{"moves out of order", "e5 e4", scholarsMate, false},
+		{"alternatives", "1. e4 (c5|e5) 2. Bc4", scholarsMate, true},
+		{"no alternative played", "1. e4 (c5|d5)", scholarsMate, false},
+		{"numbered", "3. Qh5", scholarsMate, true},
+		{"another number", "2. Qh5", scholarsMate, false},
+		{"black move number", "3... Nf6", scholarsMate, true},
+		{"white move as black", "3... Qh5", scholarsMate, false},
+		{"numbered from black move", "2... Nc6 3. Qh5", scholarsMate, true},
+		{"any capture", "x*", scholarsMate, true},
+		{"no capture", "x*", "1. e4 e5 2. Nf3", false},
+		{"capture on square", "Bxf7+ Kx*", bishopCheck, true},
+		{"move is not a capture", "Bc4 Nxc6", scholarsMate, false},
+		{"check", "* Bxf7+", bishopCheck, true},
+		{"check without capture", "* Bf7+", bishopCheck, true},
+		{"move is not a check", "Qh5+", scholarsMate, false},
+		{"mate", "Qxf7#", scholarsMate, true},
+		{"mate is a check", "Qxf7+", scholarsMate, true},
+		{"any mate", "x*#", scholarsMate, true},
+		{"check is not a mate", "Bxf7#", bishopCheck, false},
+		{"black move first", "... * Bxf7+", bishopCheck, true},
+		{"white move first", "... Bc4", bishopCheck, false},
+		{"black move then white one", "... * Qh5", scholarsMate, true},
+		{"castling", "O-O", castled, true},
+		{"castling with zeros", "3... Bc5 4. 0-0", castled, true},
+		{"long castling", "O-O-O", castled, false},
+		{"promotion", "bxa8=Q", promoted, true},
+		{"another promotion", "x*=N", promoted, false},
+		{"pawn moves", "P* P* P*", promoted, true},
+		{"pattern past the game end", "4. Qxf7# *", scholarsMate, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pattern, err := ParseMovePattern(test.pattern)
+			if err != nil {
+				t.Fatalf("ParseMovePattern(%q) error = %v", test.pattern, err)
+			}
+			if got := pattern.Match(parseTestGame(t, test.game)); got != test.want {
+				t.Errorf("%q matches %q = %v, want %v", test.pattern, test.game, got, test.want)
+			}
+		})
+	}
+}
+
+func TestMovePatternMatchEnding(t *testing.T) {
+	game := parseTestGame(t, "1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7#")
+
+	tests := []struct {
+		name        string
+		pattern     string
+		first, last int
+		want        bool
+	}{
+		{"ends on the first ply", "Bc4", 3, 3, true},
+		{"ends before the window", "Bc4", 4, 7, false},
+		{"ends after the window", "Qh5", 0, 4, false},
+		{"window before ply 0", "e4", -4, 1, true},
+		{"numbered within the window", "2. Bc4", 3, 10, true},
+		{"numbered before the window", "2. Bc4", 4, 10, false},
+		{"black move within the window", "... Nf6", 0, 7, true},
+		{"black move ends before the window", "... Nc6", 5, 7, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pattern, err := ParseMovePattern(test.pattern)
+			if err != nil {
+				t.Fatalf("ParseMovePattern(%q) error = %v", test.pattern, err)
+			}
+			if got := pattern.MatchEnding(game, test.first, test.last); got != test.want {
+				t.Errorf("MatchEnding(%d, %d) = %v, want %v", test.first, test.last, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseMovePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		valid   bool
+		end     int // -1 if not numbered
+		prefix  int // exact moves from the start
+	}{
+		{"1. e4 e5 2. Bc4 Nc6 3. Qh5 Nf6 4. Qxf7#", true, 7, 7},
+		{"1. e4 c5 2. Nf3 * 3. d4", true, 5, 3},
+		{"2. Nf3 Nc6", true, 4, 0},
+		{"5... Nf6", true, 10, 0},
+		{"... 5... Nf6", true, 10, 0},
+		{"* Bxf7+", true, -1, 0},
+		{"e4 e5", true, -1, 0},
+		{"", false, 0, 0},
+		{"1.", false, 0, 0},
+		{"Qxf9", false, 0, 0},
+		{"1. e4 3. Nf3", false, 0, 0},
+		{"0. e4", false, 0, 0},
+		{"e4 ... e5", false, 0, 0},
+		{"... 2. Bc4", false, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			pattern, err := ParseMovePattern(test.pattern)
+			if valid := err == nil; valid != test.valid {
+				t.Fatalf("ParseMovePattern(%q) error = %v, want valid %v", test.pattern, err, test.valid)
+			}
+			if !test.valid {
+				return
+			}
+			if end, ok := pattern.End(); ok != (test.end >= 0) || ok && end != test.end {
+				t.Errorf("End() = %d, %v, want %d", end, ok, test.end)
+			}
+			prefix, ok := pattern.Prefix()
+			if ok != (test.prefix > 0) || ok && len(prefix.Moves()) != test.prefix {
+				t.Errorf("Prefix() = %v, %v, want %d moves", prefix, ok, test.prefix)
+			}
+		})
+	}
+}
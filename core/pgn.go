@@ -0,0 +1,179 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/notnil/chess"
+)
+
+var (
+	moveNumberRe  = regexp.MustCompile(`^\d+\.+`)
+	fenTagRe      = regexp.MustCompile(`^\[\s*FEN\s+"([^"]*)"\s*\]$`)
+	pgnTagEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	castlingZeros = strings.NewReplacer("0-0-0", "O-O-O", "0-0", "O-O")
+	startingFEN   = chess.NewGame().Position().String()
+)
+
+// ParsePGN reads the main line of the game, headers, comments,
+// variations and annotations are skipped; unlike the underlying
+// package it fails on the first illegal move instead of ignoring it
+func ParsePGN(pgn string) (*chess.Game, error) {
+	fen, tokens, err := scanPGN(pgn)
+	if err != nil {
+		return nil, err
+	}
+
+	game := chess.NewGame()
+	if len(fen) > 0 {
+		opt, err := chess.FEN(fen)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fen header: %w", err)
+		}
+		game = chess.NewGame(opt)
+	}
+
+	var notation chess.AlgebraicNotation
+	for _, token := range tokens {
+		move, err := notation.Decode(game.Position(), token)
+		if err != nil {
+			return nil, fmt.Errorf("illegal move %s at ply %d", token, len(game.Moves())+1)
+		}
+		if err := game.Move(move); err != nil {
+			return nil, fmt.Errorf("illegal move %s at ply %d: %w", token, len(game.Moves())+1, err)
+		}
+	}
+
+	return game, nil
+}
+
+// scanPGN splits the main line into moves
+func scanPGN(pgn string) (fen string, moves []string, err error) {
+	var (
+		token     strings.Builder
+		variation int
+	)
+
+	flush := func() {
+		if variation == 0 && token.Len() > 0 {
+			if move := pgnMove(token.String()); len(move) > 0 {
+				moves = append(moves, move)
+			}
+		}
+		token.Reset()
+	}
+
+	for i := 0; i < len(pgn); i++ {
+		switch c := pgn[i]; {
+		case c == '{':
+			flush()
+			end := strings.IndexByte(pgn[i:], '}')
+			if end < 0 {
+				return "", nil, fmt.Errorf("unclosed comment")
+			}
+			i += end
+		case c == ';':
+			flush()
+			if end := strings.IndexByte(pgn[i:], '\n'); end < 0 {
+				i = len(pgn)
+			} else {
+				i += end
+			}
+		case c == '[' && variation == 0:
+			flush()
+			end := strings.IndexByte(pgn[i:], ']')
+			if end < 0 {
+				return "", nil, fmt.Errorf("unclosed header")
+			}
+			if matches := fenTagRe.FindStringSubmatch(pgn[i : i+end+1]); matches != nil {
+				fen = matches[1]
+			}
+			i += end
+		case c == '(':
+			flush()
+			variation++
+		case c == ')':
+			flush()
+			if variation == 0 {
+				return "", nil, fmt.Errorf("unexpected variation end")
+			}
+			variation--
+		case unicode.IsSpace(rune(c)):
+			flush()
+		default:
+			token.WriteByte(c)
+		}
+	}
+	flush()
+
+	if variation > 0 {
+		return "", nil, fmt.Errorf("unclosed variation")
+	}
+
+	return
+}
+
+// pgnMove strips move number and annotations from the token,
+// results and NAGs are dropped, castling with zeros is spelled
+// with letters, as the algebraic notation decoder expects
+func pgnMove(token string) string {
+	token = moveNumberRe.ReplaceAllString(token, "")
+	token = strings.TrimRight(token, "!?")
+	switch {
+	case strings.HasPrefix(token, "$"):
+		return ""
+	case token == "1-0", token == "0-1", token == "1/2-1/2", token == "*":
+		return ""
+	case strings.HasPrefix(token, "0-0"):
+		return castlingZeros.Replace(token)
+	}
+	return token
+}
+
+// FormatPGN writes the game moves, the starting position
+// is put to the headers if it's not the standard one
+func FormatPGN(game *chess.Game) string {
+	var pgn strings.Builder
+	if start := game.Positions()[0].String(); start != startingFEN {
+		fmt.Fprintf(&pgn, "[FEN \"%s\"]\n[SetUp \"1\"]\n\n", start)
+	}
+	writeMoves(&pgn, game, "")
+	return strings.TrimSpace(pgn.String())
+}
+
+// writeMoves numbers the moves from the starting position,
+// the comment goes after the first move
+func writeMoves(w io.Writer, game *chess.Game, comment string) {
+	positions := game.Positions()
+
+	number := 1
+	if fen := strings.Split(positions[0].String(), " "); len(fen) == 6 {
+		if moveNum, err := MoveNumber(fen); err == nil && moveNum > 0 {
+			number = int(moveNum)
+		}
+	}
+
+	var notation chess.AlgebraicNotation
+	var commented bool
+	for i, move := range game.Moves() {
+		position := positions[i]
+		switch {
+		case position.Turn() == chess.White:
+			fmt.Fprintf(w, "%d. ", number)
+		case i == 0 || commented:
+			fmt.Fprintf(w, "%d... ", number)
+		}
+		fmt.Fprintf(w, "%s ", notation.Encode(position, move))
+
+		commented = i == 0 && len(comment) > 0
+		if commented {
+			fmt.Fprintf(w, "{ %s } ", comment)
+		}
+		if position.Turn() == chess.Black {
+			number++
+		}
+	}
+}
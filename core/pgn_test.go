@@ -0,0 +1,91 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePGN(t *testing.T) {
+	const castled = "r1bqk1nr/pppp1ppp/2n5/2b1p3/2B1P3/5N2/PPPP1PPP/RNBQ1RK1 b kq - 5 4"
+	const setUp = "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"
+
+	tests := []struct {
+		name  string
+		pgn   string
+		fen   string // position reached
+		plies int
+	}{
+		{"moves", italianGame + " Bc5 4. O-O", castled, 7},
+		{"castling with zeros", italianGame + " Bc5 4. 0-0", castled, 7},
+		{"headers and result", "[Event \"Casual\"]\n[White \"?\"]\n\n" + italianGame + " Bc5 4. O-O 1-0", castled, 7},
+		{"comments", "1. e4 { best by test } e5 2. Nf3 ; rest of line\nNc6 3. Bc4 Bc5 4. O-O", castled, 7},
+		{"variations", "1. e4 e5 (1... c5 2. Nf3 (2. c3)) 2. Nf3 Nc6 3. Bc4 (3. Bb5 a6) Bc5 4. O-O", castled, 7},
+		{"nags and annotations", "1. e4! e5 $1 2. Nf3 Nc6?! 3. Bc4!? $14 Bc5 4. O-O!!", castled, 7},
+		{"black move number", "1. e4 e5 2. Nf3 2... Nc6 3. Bc4 3... Bc5 4. O-O", castled, 7},
+		{"fen tag", `[FEN "` + setUp + `"] [SetUp "1"] 3. Bc4 Bc5 4. 0-0`, castled, 3},
+		{"fen tag without moves", `[FEN "` + setUp + `"]`, setUp, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game, err := ParsePGN(test.pgn)
+			if err != nil {
+				t.Fatalf("ParsePGN() error = %v", err)
+			}
+			if fen := game.Position().String(); fen != test.fen || len(game.Moves()) != test.plies {
+				t.Errorf("reached %s after %d plies, want %s after %d", fen, len(game.Moves()), test.fen, test.plies)
+			}
+		})
+	}
+}
+
+func TestParsePGNErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		pgn  string
+		err  string
+	}{
+		{"illegal move", "1. e4 e4", "illegal move e4 at ply 2"},
+		{"castling through pieces", "1. e4 e5 2. 0-0", "illegal move O-O at ply 3"},
+		{"unclosed comment", "1. e4 { best", "unclosed comment"},
+		{"unclosed variation", "1. e4 (1. d4", "unclosed variation"},
+		{"unexpected variation end", "1. e4 )", "unexpected variation end"},
+		{"unclosed header", `[FEN "8/8`, "unclosed header"},
+		{"invalid fen", `[FEN "8/8 w"] 1. e4`, "invalid fen header"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParsePGN(test.pgn); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("ParsePGN() error = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestFormatPGN(t *testing.T) {
+	const setUp = "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"
+	const blackToMove = "r1bqkbnr/pppp1ppp/2n5/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 3"
+
+	tests := []struct {
+		name string
+		pgn  string
+		want string
+	}{
+		{"from start", italianGame, "1. e4 e5 2. Nf3 Nc6 3. Bc4"},
+		{"from fen", `[FEN "` + setUp + `"] 3. Bc4 Bc5`, "[FEN \"" + setUp + "\"]\n[SetUp \"1\"]\n\n3. Bc4 Bc5"},
+		{"black to move", `[FEN "` + blackToMove + `"] 3... Bc5 4. O-O`, "[FEN \"" + blackToMove + "\"]\n[SetUp \"1\"]\n\n3... Bc5 4. O-O"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			game := parseTestChessGame(t, test.pgn)
+			if got := FormatPGN(game); got != test.want {
+				t.Errorf("FormatPGN() = %q, want %q", got, test.want)
+			}
+			if _, err := ParsePGN(FormatPGN(game)); err != nil {
+				t.Errorf("formatted pgn doesn't parse: %v", err)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package core
 
 import (
-	"crypto/md5"
+	"bytes"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/notnil/chess"
 )
@@ -13,6 +15,14 @@ func PositionFromChess(p *chess.Position) Position {
 	return Position{*p}
 }
 
+func ParseFEN(fen string) (*chess.Position, error) {
+	opt, err := chess.FEN(strings.TrimSpace(fen))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fen: %w", err)
+	}
+	return chess.NewGame(opt).Position(), nil
+}
+
 func (p Position) GobEncode() (out []byte, err error) {
 	if p.Board() == nil {
 		return nil, nil
@@ -35,10 +45,160 @@ func (p *Position) GobDecode(data []byte) (err error) {
 	return
 }
 
-func (p Position) Hash() [16]byte {
-	// (p)oop moment
-	// underlying package detects en-passant
-	// square incorrectly, thus requires to
-	// implement this bug prone hash function
-	return md5.Sum([]byte(p.Board().String()))
+// Hash returns 64-bit zobrist hash of the position, which
+// includes piece placement, side to move, castling rights
+// and en-passant file (only if the capture is possible)
+func (p Position) Hash() (h uint64) {
+	board := p.Board()
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		if piece := board.Piece(sq); piece != chess.NoPiece {
+			h ^= zobrist.pieces[piece-1][sq]
+		}
+	}
+
+	if p.Turn() == chess.Black {
+		h ^= zobrist.blackTurn
+	}
+
+	rights := p.CastleRights()
+	for i, side := range [...]chess.Side{chess.KingSide, chess.QueenSide} {
+		if rights.CanCastle(chess.White, side) {
+			h ^= zobrist.castling[i]
+		}
+		if rights.CanCastle(chess.Black, side) {
+			h ^= zobrist.castling[i+2]
+		}
+	}
+
+	if sq := p.EnPassantSquare(); sq != chess.NoSquare {
+		h ^= zobrist.enPassant[sq.File()]
+	}
+
+	return
+}
+
+// EnPassantSquare returns en-passant square only if side to move
+// can actually capture on it, underlying package sets it after
+// every double pawn push
+func (p Position) EnPassantSquare() chess.Square {
+	sq := p.Position.EnPassantSquare()
+	if sq == chess.NoSquare {
+		return sq
+	}
+
+	turn := p.Turn()
+	rank := chess.Rank5
+	if turn == chess.Black {
+		rank = chess.Rank4
+	}
+
+	var adjacent bool
+	board := p.Board()
+	pawn := chess.NewPiece(chess.Pawn, turn)
+	for _, file := range [...]chess.File{sq.File() - 1, sq.File() + 1} {
+		if chess.FileA <= file && file <= chess.FileH {
+			if board.Piece(chess.NewSquare(file, rank)) == pawn {
+				adjacent = true
+			}
+		}
+	}
+	if !adjacent {
+		return chess.NoSquare
+	}
+
+	// adjacent pawn can still be pinned
+	for _, move := range p.ValidMoves() {
+		if move.HasTag(chess.EnPassant) {
+			return sq
+		}
+	}
+
+	return chess.NoSquare
+}
+
+// zobrist keys must stay the same between builds,
+// since position hashes are stored in the indexes
+var zobrist = newZobristKeys(0x636f7073) // "cops"
+
+type zobristKeys struct {
+	pieces    [12][64]uint64
+	blackTurn uint64
+	castling  [4]uint64
+	enPassant [8]uint64
+}
+
+func newZobristKeys(seed uint64) (keys zobristKeys) {
+	// splitmix64
+	next := func() uint64 {
+		seed += 0x9e3779b97f4a7c15
+		return mix64(seed)
+	}
+
+	for piece := range keys.pieces {
+		for sq := range keys.pieces[piece] {
+			keys.pieces[piece][sq] = next()
+		}
+	}
+	keys.blackTurn = next()
+	for i := range keys.castling {
+		keys.castling[i] = next()
+	}
+	for i := range keys.enPassant {
+		keys.enPassant[i] = next()
+	}
+
+	return
+}
+
+// PositionsIndexDepth limits how many plies
+// of each game are stored in the positions index
+const PositionsIndexDepth = 60
+
+// PositionsIndex maps position hash to sorted
+// ids of the games passing through the position
+type PositionsIndex map[uint64][]GameID
+
+func (i PositionsIndex) InsertFromChess(id GameID, game *chess.Game) {
+	positions := game.Positions()
+	if len(positions) > PositionsIndexDepth+1 {
+		positions = positions[:PositionsIndexDepth+1]
+	}
+	for _, position := range positions[1:] { // skip the starting one
+		hash := PositionFromChess(position).Hash()
+		// game positions are inserted in a row,
+		// so repetitions are at the end only
+		if ids := i[hash]; len(ids) == 0 || ids[len(ids)-1] != id {
+			i[hash] = append(ids, id)
+		}
+	}
+}
+
+func (i PositionsIndex) Merge(other PositionsIndex) {
+	for hash, ids := range other {
+		i[hash] = append(i[hash], ids...)
+	}
+}
+
+// Sort must be called after inserts, as
+// Contains relies on the game ids order
+func (i PositionsIndex) Sort() {
+	for _, ids := range i {
+		slices.SortFunc(ids, compareGameIDs)
+	}
+}
+
+func (i PositionsIndex) Contains(hash uint64, id GameID) bool {
+	_, found := slices.BinarySearchFunc(i[hash], id, compareGameIDs)
+	return found
+}
+
+func compareGameIDs(a, b GameID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+// mix64 is the splitmix64 finalizer
+func mix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
 }
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestPositionHash(t *testing.T) {
+	const start = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR"
+
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{"same position", start + " w KQkq - 0 1", start + " w KQkq - 0 1", true},
+		{"move counters are ignored", start + " w KQkq - 0 1", start + " w KQkq - 4 3", true},
+		{"another side to move", start + " w KQkq - 0 1", start + " b KQkq - 0 1", false},
+		{"another castling rights", start + " w KQkq - 0 1", start + " w Kkq - 0 1", false},
+		{"no castling rights", start + " w KQkq - 0 1", start + " w - - 0 1", false},
+		{
+			"en passant can be captured",
+			"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+			"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq - 0 3",
+			false,
+		},
+		{
+			"en passant without adjacent pawn",
+			"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2",
+			"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+			true,
+		},
+		{
+			"en passant by pinned pawn",
+			"3r3k/8/8/3Pp3/8/8/8/3K4 w - e6 0 2",
+			"3r3k/8/8/3Pp3/8/8/8/3K4 w - - 0 2",
+			true,
+		},
+		{
+			"en passant exposing the king",
+			"7k/8/8/KPp4r/8/8/8/8 w - c6 0 2",
+			"7k/8/8/KPp4r/8/8/8/8 w - - 0 2",
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := parseTestPosition(t, test.a), parseTestPosition(t, test.b)
+			if same := a.Hash() == b.Hash(); same != test.same {
+				t.Errorf("Hash(%q) == Hash(%q) is %v, want %v", test.a, test.b, same, test.same)
+			}
+		})
+	}
+}
+
+func TestPositionsIndexContains(t *testing.T) {
+	lines := map[string]string{
+		"game0003": "1. e4 e5 2. Nf3 Nc6",
+		"game0001": "1. Nf3 Nc6 2. e4 e5",
+		"game0002": "1. d4 d5 2. c4",
+	}
+
+	index := make(PositionsIndex)
+	other := make(PositionsIndex)
+	for id, line := range lines {
+		game, err := ParsePGN(line)
+		if err != nil {
+			t.Fatalf("invalid test moves %q: %v", line, err)
+		}
+		if id == "game0002" {
+			other.InsertFromChess(ParseGameID(id), game)
+		} else {
+			index.InsertFromChess(ParseGameID(id), game)
+		}
+	}
+	index.Merge(other)
+	index.Sort()
+
+	tests := []struct {
+		name string
+		fen  string
+		id   string
+		want bool
+	}{
+		{"transposed position", "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", "game0001", true},
+		{"main line position", "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", "game0003", true},
+		{"merged game position", "rnbqkbnr/ppp1pppp/8/3p4/2PP4/8/PP2PPPP/RNBQKBNR b KQkq - 0 2", "game0002", true},
+		{"position of another game", "rnbqkbnr/ppp1pppp/8/3p4/2PP4/8/PP2PPPP/RNBQKBNR b KQkq - 0 2", "game0001", false},
+		{"starting position is skipped", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "game0001", false},
+		{"unknown game", "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", "game0004", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hash := parseTestPosition(t, test.fen).Hash()
+			if got := index.Contains(hash, ParseGameID(test.id)); got != test.want {
+				t.Errorf("Contains(%s) = %v, want %v", test.id, got, test.want)
+			}
+		})
+	}
+}
+
+func parseTestPosition(t *testing.T, fen string) Position {
+	t.Helper()
+	return PositionFromChess(parseTestChessPosition(t, fen))
+}
+
+func parseTestChessPosition(t *testing.T, fen string) *chess.Position {
+	t.Helper()
+	position, err := ParseFEN(fen)
+	if err != nil {
+		t.Fatalf("invalid test position %q: %v", fen, err)
+	}
+	return position
+}
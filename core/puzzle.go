@@ -1,12 +1,16 @@
 package core
 
 import (
+	"bytes"
+	"cmp"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"iter"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
-	"unsafe"
 
 	"github.com/notnil/chess"
 )
@@ -22,29 +26,94 @@ func (id PuzzleID) String() string {
 	return string(id[:])
 }
 
+func (id PuzzleID) MarshalText() ([]byte, error) {
+	return id[:], nil
+}
+
+func (id *PuzzleID) UnmarshalText(text []byte) error {
+	if len(text) != len(id) {
+		return fmt.Errorf("invalid puzzle id: %q", text)
+	}
+	copy(id[:], text)
+	return nil
+}
+
+// PuzzleRecord is a row of the lichess puzzles database
+type PuzzleRecord struct {
+	ID              string
+	FEN             string
+	Moves           string
+	Rating          string
+	RatingDeviation string
+	Popularity      string
+	NbPlays         string
+	Themes          string
+	GameURL         string
+	OpeningTags     string
+}
+
 type PuzzleData struct {
-	Move   uint8
-	Turn   chess.Color
-	ID     PuzzleID
-	GameID GameID
+	Move            uint8
+	Turn            chess.Color
+	ID              PuzzleID
+	GameID          GameID
+	Rating          uint16
+	RatingDeviation uint16
+	Popularity      int8
+	Plays           uint32
+	Themes          Themes
 }
 
-func NewPuzzleData(id, gameURL, fen string) (d PuzzleData, err error) {
-	fenParts := strings.Split(fen, " ")
+func NewPuzzleData(r PuzzleRecord) (d PuzzleData, err error) {
+	fenParts := strings.Split(r.FEN, " ")
 	if len(fenParts) != 6 {
 		err = fmt.Errorf("invalid fen format")
 		return
 	}
 
-	d.Move, err = MoveNumber(fenParts)
-	d.Turn, err = PlayingTurn(fenParts)
+	if d.Move, err = MoveNumber(fenParts); err != nil {
+		return
+	}
+	if d.Turn, err = PlayingTurn(fenParts); err != nil {
+		return
+	}
 
 	// puzzle saved position is one ply behind
 	// thus it has an inverse turn encoded
 	d.Turn = d.Turn.Other()
 
-	d.ID = ParsePuzzleID(id)
-	d.GameID = ParseGameIDFromURL(gameURL)
+	d.ID = ParsePuzzleID(r.ID)
+	d.GameID = ParseGameIDFromURL(r.GameURL)
+
+	rating, err := strconv.ParseUint(r.Rating, 10, 16)
+	if err != nil {
+		err = fmt.Errorf("invalid rating: %v", err)
+		return
+	}
+	d.Rating = uint16(rating)
+
+	deviation, err := strconv.ParseUint(r.RatingDeviation, 10, 16)
+	if err != nil {
+		err = fmt.Errorf("invalid rating deviation: %v", err)
+		return
+	}
+	d.RatingDeviation = uint16(deviation)
+
+	popularity, err := strconv.ParseInt(r.Popularity, 10, 8)
+	if err != nil {
+		err = fmt.Errorf("invalid popularity: %v", err)
+		return
+	}
+	d.Popularity = int8(popularity)
+
+	plays, err := strconv.ParseUint(r.NbPlays, 10, 32)
+	if err != nil {
+		err = fmt.Errorf("invalid plays number: %v", err)
+		return
+	}
+	d.Plays = uint32(plays)
+
+	d.Themes = ParseThemes(r.Themes)
 
 	return
 }
@@ -54,8 +123,76 @@ func (d PuzzleData) URL() (url string) {
 	return
 }
 
+// GameURL links the source game position the puzzle starts from
+func (d PuzzleData) GameURL() string {
+	return "https://lichess.org/" + d.GameID.String() + "#" + strconv.Itoa(d.Ply())
+}
+
+// Ply returns the number of half-moves played in the source
+// game before the puzzle position, opponent's move included
+func (d PuzzleData) Ply() int {
+	ply := (int(d.Move)-1)*2 + 1
+	if d.Turn == chess.White {
+		ply++
+	}
+	return ply
+}
+
+type puzzleJSON struct {
+	ID              string   `json:"id"`
+	URL             string   `json:"url"`
+	GameID          string   `json:"game_id"`
+	Move            uint8    `json:"move"`
+	Turn            string   `json:"turn"`
+	Rating          uint16   `json:"rating"`
+	RatingDeviation uint16   `json:"rating_deviation"`
+	Popularity      int8     `json:"popularity"`
+	Plays           uint32   `json:"plays"`
+	Themes          []string `json:"themes"`
+}
+
+func (d PuzzleData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(puzzleJSON{
+		ID:              d.ID.String(),
+		URL:             d.URL(),
+		GameID:          d.GameID.String(),
+		Move:            d.Move,
+		Turn:            d.Turn.Name(),
+		Rating:          d.Rating,
+		RatingDeviation: d.RatingDeviation,
+		Popularity:      d.Popularity,
+		Plays:           d.Plays,
+		Themes:          strings.Fields(d.Themes.String()),
+	})
+}
+
+func (d *PuzzleData) UnmarshalJSON(data []byte) error {
+	var p puzzleJSON
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	turn, err := ParseTurn(p.Turn)
+	if err != nil {
+		return fmt.Errorf("puzzle %s: %w", p.ID, err)
+	}
+
+	*d = PuzzleData{
+		Move:            p.Move,
+		Turn:            turn.ToChess(),
+		ID:              ParsePuzzleID(p.ID),
+		GameID:          ParseGameID(p.GameID),
+		Rating:          p.Rating,
+		RatingDeviation: p.RatingDeviation,
+		Popularity:      p.Popularity,
+		Plays:           p.Plays,
+		Themes:          ParseThemes(strings.Join(p.Themes, " ")),
+	}
+	return nil
+}
+
 func (d PuzzleData) GobEncode() (out []byte, err error) {
-	out = make([]byte, unsafe.Sizeof(d))
+	out = make([]byte, binary.Size(d))
 	_, err = binary.Encode(out, binary.LittleEndian, d)
 	if err != nil {
 		err = fmt.Errorf("puzzle encode: %w", err)
@@ -73,13 +210,13 @@ func (d *PuzzleData) GobDecode(data []byte) (err error) {
 
 type PuzzlesIndex map[string][]PuzzleData
 
-func (i PuzzlesIndex) Insert(puzzleID, fen, gameURL, openingTags string) error {
-	puzzle, err := NewPuzzleData(puzzleID, gameURL, fen)
+func (i PuzzlesIndex) Insert(record PuzzleRecord) error {
+	puzzle, err := NewPuzzleData(record)
 	if err != nil {
 		return fmt.Errorf("failed to parse puzzle: %w", err)
 	}
 
-	tags := strings.Split(openingTags, " ")
+	tags := strings.Split(record.OpeningTags, " ")
 	for _, tag := range tags {
 		i[tag] = append(i[tag], puzzle)
 	}
@@ -87,11 +224,55 @@ func (i PuzzlesIndex) Insert(puzzleID, fen, gameURL, openingTags string) error {
 	return nil
 }
 
-func (i PuzzlesIndex) Filter(openingTag string, side chess.Color, maxMoves uint8) iter.Seq[PuzzleData] {
+// PuzzleFilter limits found puzzles, zero rating bounds are ignored;
+// popularity runs from -100 to 100, so it's not limited only if unset
+type PuzzleFilter struct {
+	Turn           chess.Color
+	MaxMoves       uint8
+	MinRating      uint16
+	MaxRating      uint16
+	MinPopularity  *int8
+	Themes         Themes // all are required
+	ExcludedThemes Themes
+}
+
+func (f PuzzleFilter) Matches(puzzle PuzzleData) bool {
+	return (f.Turn == chess.NoColor || puzzle.Turn == f.Turn) &&
+		puzzle.Move <= f.MaxMoves &&
+		puzzle.Rating >= f.MinRating &&
+		(f.MaxRating == 0 || puzzle.Rating <= f.MaxRating) &&
+		(f.MinPopularity == nil || puzzle.Popularity >= *f.MinPopularity) &&
+		puzzle.Themes.ContainsAll(f.Themes) &&
+		!puzzle.Themes.ContainsAny(f.ExcludedThemes)
+}
+
+func (i PuzzlesIndex) Filter(openingTag string, filter PuzzleFilter) iter.Seq[PuzzleData] {
 	return func(yield func(PuzzleData) bool) {
 		for _, puzzle := range i[openingTag] {
-			if side == chess.NoColor || puzzle.Turn == side {
-				if puzzle.Move <= maxMoves {
+			if filter.Matches(puzzle) {
+				if !yield(puzzle) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// FilterAll yields puzzles of all the openings, puzzle
+// tagged with several openings is yielded only once
+func (i PuzzlesIndex) FilterAll(filter PuzzleFilter) iter.Seq[PuzzleData] {
+	return i.FilterTags(slices.Collect(maps.Keys(i)), filter)
+}
+
+// FilterTags yields puzzles of any of the openings, puzzle
+// tagged with several of them is yielded only once
+func (i PuzzlesIndex) FilterTags(openingTags []string, filter PuzzleFilter) iter.Seq[PuzzleData] {
+	return func(yield func(PuzzleData) bool) {
+		seen := make(map[PuzzleID]struct{})
+		for _, tag := range openingTags {
+			for puzzle := range i.Filter(tag, filter) {
+				if _, ok := seen[puzzle.ID]; !ok {
+					seen[puzzle.ID] = struct{}{}
 					if !yield(puzzle) {
 						return
 					}
@@ -101,6 +282,75 @@ func (i PuzzlesIndex) Filter(openingTag string, side chess.Color, maxMoves uint8
 	}
 }
 
+type SortOrder int8
+
+const (
+	NoSort SortOrder = iota
+	SortByRating
+	SortByPopularity
+	SortByMove
+)
+
+func (o SortOrder) String() string {
+	switch o {
+	case NoSort:
+		return "None"
+	case SortByRating:
+		return "Rating"
+	case SortByPopularity:
+		return "Popularity"
+	case SortByMove:
+		return "Move"
+	default:
+		panic("unreachable")
+	}
+}
+
+func ParseSortOrder(s string) (o SortOrder, err error) {
+	switch strings.ToLower(s) {
+	case "none", "":
+		o = NoSort
+	case "rating":
+		o = SortByRating
+	case "popularity":
+		o = SortByPopularity
+	case "move":
+		o = SortByMove
+	default:
+		err = fmt.Errorf("invalid sort order: %s", s)
+	}
+	return
+}
+
+// SortPuzzles orders by rating and move number ascending,
+// by popularity descending, puzzle id breaks the ties
+func SortPuzzles(puzzles []PuzzleData, order SortOrder) {
+	var compare func(a, b PuzzleData) int
+	switch order {
+	case SortByRating:
+		compare = func(a, b PuzzleData) int {
+			return cmp.Compare(a.Rating, b.Rating)
+		}
+	case SortByPopularity:
+		compare = func(a, b PuzzleData) int {
+			return cmp.Compare(b.Popularity, a.Popularity)
+		}
+	case SortByMove:
+		compare = func(a, b PuzzleData) int {
+			return cmp.Compare(a.Ply(), b.Ply())
+		}
+	default:
+		return
+	}
+
+	slices.SortFunc(puzzles, func(a, b PuzzleData) int {
+		if c := compare(a, b); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+}
+
 func MoveNumber(fen []string) (n uint8, err error) {
 	v, err := strconv.ParseUint(fen[5], 10, 8)
 	if err != nil {
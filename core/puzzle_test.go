@@ -0,0 +1,49 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestPuzzleFilterMatches(t *testing.T) {
+	popularity := func(p int8) *int8 { return &p }
+	fork, mateIn2 := ParseThemes("fork"), ParseThemes("mateIn2")
+
+	puzzle := PuzzleData{
+		Move:       12,
+		Turn:       chess.Black,
+		Rating:     1600,
+		Popularity: -10,
+		Themes:     ParseThemes("fork middlegame"),
+	}
+
+	tests := []struct {
+		name   string
+		filter PuzzleFilter
+		want   bool
+	}{
+		{"no limits", PuzzleFilter{MaxMoves: 40}, true},
+		{"turn", PuzzleFilter{Turn: chess.Black, MaxMoves: 40}, true},
+		{"another turn", PuzzleFilter{Turn: chess.White, MaxMoves: 40}, false},
+		{"move limit", PuzzleFilter{MaxMoves: 12}, true},
+		{"move over limit", PuzzleFilter{MaxMoves: 11}, false},
+		{"rating range", PuzzleFilter{MaxMoves: 40, MinRating: 1500, MaxRating: 1600}, true},
+		{"rating under min", PuzzleFilter{MaxMoves: 40, MinRating: 1700}, false},
+		{"rating over max", PuzzleFilter{MaxMoves: 40, MaxRating: 1500}, false},
+		{"popularity under min", PuzzleFilter{MaxMoves: 40, MinPopularity: popularity(0)}, false},
+		{"negative min popularity", PuzzleFilter{MaxMoves: 40, MinPopularity: popularity(-10)}, true},
+		{"lowest popularity", PuzzleFilter{MaxMoves: 40, MinPopularity: popularity(-100)}, true},
+		{"required theme", PuzzleFilter{MaxMoves: 40, Themes: fork}, true},
+		{"missing theme", PuzzleFilter{MaxMoves: 40, Themes: mateIn2}, false},
+		{"excluded theme", PuzzleFilter{MaxMoves: 40, ExcludedThemes: fork}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.filter.Matches(puzzle); got != test.want {
+				t.Errorf("Matches() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
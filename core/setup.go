@@ -0,0 +1,208 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// Setup is the position set up piece by piece instead of
+// reached by moves, e.g. the one seen over the board
+type Setup struct {
+	Board    Board
+	Turn     chess.Color
+	Castling chess.CastleRights // e.g. KQq, empty if none
+	// EnPassant is the square behind the pawn which has just
+	// moved two squares, e.g. e6, empty if none
+	EnPassant string
+	// MoveNumber is the full move number, the position
+	// is searched in the games from this move
+	MoveNumber int
+}
+
+// SetupFromChess takes the position to edit it further
+func SetupFromChess(position *chess.Position) Setup {
+	moveNum, err := MoveNumber(strings.Split(position.String(), " "))
+	if err != nil || moveNum == 0 {
+		moveNum = 1
+	}
+	castling := position.CastleRights()
+	if castling == "-" {
+		castling = ""
+	}
+	var enPassant string
+	if sq := position.EnPassantSquare(); sq != chess.NoSquare {
+		enPassant = sq.String()
+	}
+	return Setup{
+		Board:      BoardFromChess(position.Board()),
+		Turn:       position.Turn(),
+		Castling:   castling,
+		EnPassant:  enPassant,
+		MoveNumber: int(moveNum),
+	}
+}
+
+// castlingSquares are the king and rook squares the castling rights require
+var castlingSquares = []struct {
+	right string
+	king  chess.Square
+	rook  chess.Square
+}{
+	{"K", chess.E1, chess.H1},
+	{"Q", chess.E1, chess.A1},
+	{"k", chess.E8, chess.H8},
+	{"q", chess.E8, chess.A8},
+}
+
+// Validate tells why the setup can't be reached in a game, if it can't
+func (s Setup) Validate() error {
+	var kings, pawns [2]int
+	var kingSquares [2]chess.Square
+	for sq, piece := range s.Board {
+		side := 0
+		if piece.Color() == chess.Black {
+			side = 1
+		}
+		switch piece.Type() {
+		case chess.King:
+			kings[side]++
+			kingSquares[side] = chess.Square(sq)
+		case chess.Pawn:
+			pawns[side]++
+			if rank := chess.Square(sq).Rank(); rank == chess.Rank1 || rank == chess.Rank8 {
+				return fmt.Errorf("pawn on %s can't stand on the first or last rank", chess.Square(sq))
+			}
+		}
+	}
+
+	for side, color := range [...]chess.Color{chess.White, chess.Black} {
+		if kings[side] != 1 {
+			return fmt.Errorf("%s must have exactly one king", colorName(color))
+		}
+		if pawns[side] > 8 {
+			return fmt.Errorf("%s has more than 8 pawns", colorName(color))
+		}
+	}
+
+	for _, castling := range castlingSquares {
+		if !strings.Contains(string(s.Castling), castling.right) {
+			continue
+		}
+		color := chess.White
+		if castling.right != strings.ToUpper(castling.right) {
+			color = chess.Black
+		}
+		if s.Board[castling.king] != chess.NewPiece(chess.King, color) ||
+			s.Board[castling.rook] != chess.NewPiece(chess.Rook, color) {
+			return fmt.Errorf("castling %s requires the king on %s and the rook on %s",
+				castling.right, castling.king, castling.rook)
+		}
+	}
+
+	if s.Turn != chess.White && s.Turn != chess.Black {
+		return errors.New("side to move is not set")
+	}
+	if err := s.validateEnPassant(); err != nil {
+		return err
+	}
+	if s.MoveNumber < 1 {
+		return fmt.Errorf("invalid move number %d", s.MoveNumber)
+	}
+
+	position, err := ParseFEN(s.FEN())
+	if err != nil {
+		return err
+	}
+	// the side to move can't capture the king
+	opponentKing := kingSquares[0]
+	if s.Turn == chess.White {
+		opponentKing = kingSquares[1]
+	}
+	for _, move := range position.ValidMoves() {
+		if move.S2() == opponentKing {
+			return fmt.Errorf("%s is in check, but it's %s to move", colorName(s.Turn.Other()), colorName(s.Turn))
+		}
+	}
+	return nil
+}
+
+var enPassantRe = regexp.MustCompile(`^[a-h][36]$`)
+
+// validateEnPassant checks the opponent's pawn has just moved
+// two squares past the en passant one, which must be empty
+func (s Setup) validateEnPassant() error {
+	if len(s.EnPassant) == 0 {
+		return nil
+	}
+	if !enPassantRe.MatchString(s.EnPassant) {
+		return fmt.Errorf("invalid en passant square %s", s.EnPassant)
+	}
+
+	file := chess.File(s.EnPassant[0] - 'a')
+	rank, from, to := chess.Rank6, chess.Rank7, chess.Rank5
+	if s.Turn == chess.Black {
+		rank, from, to = chess.Rank3, chess.Rank2, chess.Rank4
+	}
+	pawn := chess.NewPiece(chess.Pawn, s.Turn.Other())
+	if chess.Rank(s.EnPassant[1]-'1') != rank ||
+		s.Board[chess.NewSquare(file, to)] != pawn ||
+		s.Board[chess.NewSquare(file, rank)] != chess.NoPiece ||
+		s.Board[chess.NewSquare(file, from)] != chess.NoPiece {
+		return fmt.Errorf("en passant on %s requires %s pawn which has just moved to %s",
+			s.EnPassant, colorName(s.Turn.Other()), chess.NewSquare(file, to))
+	}
+	return nil
+}
+
+// Position validates the setup and makes the position
+func (s Setup) Position() (*chess.Position, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return ParseFEN(s.FEN())
+}
+
+// FEN writes the setup without validating it
+func (s Setup) FEN() string {
+	var fen strings.Builder
+	for rank := chess.Rank8; rank >= chess.Rank1; rank-- {
+		empty := 0
+		for file := chess.FileA; file <= chess.FileH; file++ {
+			piece := s.Board[chess.NewSquare(file, rank)]
+			if piece == chess.NoPiece {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				fmt.Fprint(&fen, empty)
+				empty = 0
+			}
+			fen.WriteString(fenPieces[piece])
+		}
+		if empty > 0 {
+			fmt.Fprint(&fen, empty)
+		}
+		if rank > chess.Rank1 {
+			fen.WriteByte('/')
+		}
+	}
+
+	castling := string(s.Castling)
+	if len(castling) == 0 {
+		castling = "-"
+	}
+	enPassant := s.EnPassant
+	if len(enPassant) == 0 {
+		enPassant = "-"
+	}
+	fmt.Fprintf(&fen, " %s %s %s 0 %d", s.Turn, castling, enPassant, max(s.MoveNumber, 1))
+	return fen.String()
+}
+
+func colorName(c chess.Color) string {
+	return TurnFromChess(c).String()
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestSetupValidate(t *testing.T) {
+	const (
+		start     = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+		kingsPawn = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"
+		rookCheck = "4k3/8/8/8/8/8/8/4RK2 b - - 0 1"
+	)
+
+	tests := []struct {
+		name   string
+		fen    string
+		modify func(*Setup)
+		err    string // empty if valid
+	}{
+		{"starting position", start, func(*Setup) {}, ""},
+		{"white pawn on the last rank", start, func(s *Setup) { s.Board[chess.A8] = chess.WhitePawn }, "pawn on a8"},
+		{"black pawn on the first rank", start, func(s *Setup) { s.Board[chess.H1] = chess.BlackPawn }, "pawn on h1"},
+		{"no white king", start, func(s *Setup) { s.Board[chess.E1] = chess.NoPiece; s.Castling = "" }, "White must have exactly one king"},
+		{"two black kings", start, func(s *Setup) { s.Board[chess.D5] = chess.BlackKing }, "Black must have exactly one king"},
+		{"nine pawns", start, func(s *Setup) { s.Board[chess.E4] = chess.WhitePawn }, "more than 8 pawns"},
+		{"castling without rook", start, func(s *Setup) { s.Board[chess.H1] = chess.NoPiece }, "castling K"},
+		{"castling with moved king", start, func(s *Setup) {
+			s.Board[chess.E8], s.Board[chess.D8] = chess.NoPiece, chess.BlackKing
+		}, "castling k"},
+		{"castling right dropped", start, func(s *Setup) { s.Board[chess.H1], s.Castling = chess.NoPiece, "Qkq" }, ""},
+		{"side to move in check", rookCheck, func(*Setup) {}, ""},
+		{"side not to move in check", rookCheck, func(s *Setup) { s.Turn = chess.White }, "Black is in check, but it's White to move"},
+		{"zero move number", start, func(s *Setup) { s.MoveNumber = 0 }, "invalid move number"},
+		{"en passant", kingsPawn, func(*Setup) {}, ""},
+		{"en passant of another side", kingsPawn, func(s *Setup) { s.Turn = chess.White }, "en passant on e3"},
+		{"en passant without pawn", kingsPawn, func(s *Setup) { s.EnPassant = "d3" }, "en passant on d3"},
+		{"en passant over a piece", kingsPawn, func(s *Setup) { s.Board[chess.E3] = chess.WhiteKnight }, "en passant on e3"},
+		{"en passant off the board", kingsPawn, func(s *Setup) { s.EnPassant = "e9" }, "invalid en passant square"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setup := SetupFromChess(parseTestChessPosition(t, test.fen))
+			test.modify(&setup)
+			err := setup.Validate()
+			switch {
+			case len(test.err) == 0 && err != nil:
+				t.Errorf("Validate() error = %v, want valid", err)
+			case len(test.err) > 0 && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Errorf("Validate() error = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestSetupFEN(t *testing.T) {
+	tests := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+		"r3k2r/8/8/8/8/8/8/R3K2R b Qk - 0 20",
+		"4k3/8/8/8/8/8/8/4K3 w - - 0 60",
+	}
+
+	for _, fen := range tests {
+		t.Run(fen, func(t *testing.T) {
+			setup := SetupFromChess(parseTestChessPosition(t, fen))
+			if got := setup.FEN(); got != fen {
+				t.Errorf("FEN() = %q, want %q", got, fen)
+			}
+			if _, err := setup.Position(); err != nil {
+				t.Errorf("Position() error = %v", err)
+			}
+		})
+	}
+}
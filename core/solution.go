@@ -0,0 +1,148 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+// PuzzleSolution starts with the opponent's move
+// played from the saved position, then the solving
+// side and the opponent moves take turns
+type PuzzleSolution struct {
+	FEN   string
+	Moves Game
+}
+
+func ParsePuzzleSolution(fen, moves string) (s PuzzleSolution, err error) {
+	opt, err := chess.FEN(fen)
+	if err != nil {
+		err = fmt.Errorf("invalid fen: %w", err)
+		return
+	}
+
+	game := chess.NewGame(opt, chess.UseNotation(chess.UCINotation{}))
+	for _, move := range strings.Fields(moves) {
+		if err = game.MoveStr(move); err != nil {
+			err = fmt.Errorf("invalid solution move %s: %w", move, err)
+			return
+		}
+	}
+
+	if len(game.Moves()) < 2 {
+		err = fmt.Errorf("solution is too short: %q", moves)
+		return
+	}
+
+	s.FEN = fen
+	s.Moves = make(Game, len(game.Moves()))
+	for i, move := range game.Moves() {
+		s.Moves[i] = GameFromChess(move)
+	}
+
+	return
+}
+
+func (s PuzzleSolution) Empty() bool {
+	return len(s.Moves) == 0
+}
+
+// Start returns the puzzle position, where
+// the opponent's move is already played
+func (s PuzzleSolution) Start() (*chess.Game, error) {
+	return s.Replay(1)
+}
+
+// Replay plays the first plies of the solution from the saved position
+func (s PuzzleSolution) Replay(plies int) (*chess.Game, error) {
+	opt, err := chess.FEN(s.FEN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fen: %w", err)
+	}
+	game := chess.NewGame(opt)
+	for _, move := range s.Moves[:min(plies, len(s.Moves))] {
+		if err := playMove(game, move); err != nil {
+			return nil, err
+		}
+	}
+	return game, nil
+}
+
+type SolveStatus int8
+
+const (
+	Solving SolveStatus = iota
+	Solved
+	Failed
+)
+
+func (s SolveStatus) String() string {
+	switch s {
+	case Solving:
+		return "Solving"
+	case Solved:
+		return "Solved"
+	case Failed:
+		return "Failed"
+	default:
+		panic("unreachable")
+	}
+}
+
+// Verify checks moves played since the puzzle start
+// position, if the opponent is to move the reply is
+// played on the game; any mate ends the puzzle, as
+// lichess accepts alternative mating moves
+func (s PuzzleSolution) Verify(game *chess.Game) (SolveStatus, error) {
+	moves := game.Moves()
+	if len(moves) > len(s.Moves) {
+		return Failed, nil
+	}
+
+	for i, move := range moves {
+		if GameFromChess(move) != s.Moves[i] {
+			if i&1 == 1 && game.Method() == chess.Checkmate && i == len(moves)-1 {
+				return Solved, nil
+			}
+			return Failed, nil
+		}
+	}
+
+	if len(moves) == len(s.Moves) {
+		return Solved, nil
+	}
+
+	if len(moves)&1 == 0 { // opponent's turn
+		if err := playMove(game, s.Moves[len(moves)]); err != nil {
+			return Failed, err
+		}
+		if len(moves)+1 == len(s.Moves) {
+			return Solved, nil // solution shouldn't end with opponent's move
+		}
+	}
+
+	return Solving, nil
+}
+
+func playMove(game *chess.Game, move Move) error {
+	for _, valid := range game.ValidMoves() {
+		if GameFromChess(valid) == move {
+			return game.Move(valid)
+		}
+	}
+	return fmt.Errorf("invalid solution move %s", move)
+}
+
+// SolutionsIndex keeps puzzles solutions apart from the puzzles
+// index, which has a copy of puzzle for every opening tag
+type SolutionsIndex map[PuzzleID]PuzzleSolution
+
+func (i SolutionsIndex) Insert(record PuzzleRecord) error {
+	solution, err := ParsePuzzleSolution(record.FEN, record.Moves)
+	if err != nil {
+		return fmt.Errorf("failed to parse puzzle solution: %w", err)
+	}
+	i[ParsePuzzleID(record.ID)] = solution
+	return nil
+}
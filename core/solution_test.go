@@ -0,0 +1,84 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestPuzzleSolutionVerify(t *testing.T) {
+	// black king walks into the corner, both rooks mate on the back rank
+	const fen = "6k1/5ppp/8/8/8/8/5PPP/3RR1K1 b - - 0 1"
+
+	tests := []struct {
+		name     string
+		solution string
+		played   []string // uci moves played since the puzzle start
+		want     SolveStatus
+		plies    int // game plies after the opponent's reply
+	}{
+		{"nothing played", "g8h8 e1e8", nil, Solving, 1},
+		{"solution move", "g8h8 e1e8", []string{"e1e8"}, Solved, 2},
+		{"alternative mate", "g8h8 e1e8", []string{"d1d8"}, Solved, 2},
+		{"wrong move", "g8h8 e1e8", []string{"e1e2"}, Failed, 2},
+		{"opponent replies", "g8h8 e1e7 h7h6 e7e8", []string{"e1e7"}, Solving, 3},
+		{"last move after reply", "g8h8 e1e7 h7h6 e7e8", []string{"e1e7", "h7h6", "e7e8"}, Solved, 4},
+		{"wrong move after reply", "g8h8 e1e7 h7h6 e7e8", []string{"e1e7", "h7h6", "e7e6"}, Failed, 4},
+		{"ends with opponent's move", "g8h8 e1e7 h7h6", []string{"e1e7"}, Solved, 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			solution, err := ParsePuzzleSolution(fen, test.solution)
+			if err != nil {
+				t.Fatalf("invalid test solution %q: %v", test.solution, err)
+			}
+			game, err := solution.Start()
+			if err != nil {
+				t.Fatalf("failed to start puzzle: %v", err)
+			}
+			for _, uci := range test.played {
+				move, err := chess.UCINotation{}.Decode(game.Position(), uci)
+				if err != nil || game.Move(move) != nil {
+					t.Fatalf("invalid test move %s: %v", uci, err)
+				}
+			}
+
+			status, err := solution.Verify(game)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if status != test.want || len(game.Moves()) != test.plies {
+				t.Errorf("Verify() = %s after %d plies, want %s after %d", status, len(game.Moves()), test.want, test.plies)
+			}
+		})
+	}
+}
+
+func TestParsePuzzleSolution(t *testing.T) {
+	const fen = "6k1/5ppp/8/8/8/8/5PPP/3RR1K1 b - - 0 1"
+
+	tests := []struct {
+		name  string
+		fen   string
+		moves string
+		valid bool
+	}{
+		{"valid", fen, "g8h8 e1e8", true},
+		{"invalid fen", "6k1/5ppp w", "g8h8 e1e8", false},
+		{"illegal move", fen, "g8h8 e1e9", false},
+		{"too short", fen, "g8h8", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			solution, err := ParsePuzzleSolution(test.fen, test.moves)
+			if valid := err == nil; valid != test.valid {
+				t.Fatalf("ParsePuzzleSolution() error = %v, want valid %v", err, test.valid)
+			}
+			if test.valid && len(solution.Moves) != 2 {
+				t.Errorf("parsed %d moves, want 2", len(solution.Moves))
+			}
+		})
+	}
+}
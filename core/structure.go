@@ -0,0 +1,69 @@
+package core
+
+import (
+	"github.com/notnil/chess"
+)
+
+// StructureSignature hashes the pawn skeleton of the position, so
+// positions with the same pawns on the same squares share it whatever
+// the pieces placement; with material the pieces count is hashed too
+func StructureSignature(position *chess.Position, material bool) (h uint64) {
+	var counts [12]uint8
+	board := position.Board()
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		switch piece := board.Piece(sq); piece.Type() {
+		case chess.NoPieceType, chess.King:
+		case chess.Pawn:
+			h ^= zobrist.pieces[piece-1][sq]
+		default:
+			counts[piece-1]++
+		}
+	}
+
+	if material {
+		var packed uint64
+		for _, count := range counts {
+			packed = packed<<4 | uint64(min(count, 15))
+		}
+		h ^= mix64(packed | 1<<63) // differs from the pawns only signature
+	}
+
+	return
+}
+
+// ReachedStructure tells whether the game reached the structure
+// from the first ply up to the last one, last is included
+func (g Game) ReachedStructure(signature uint64, material bool, first, last int) bool {
+	for ply, position := range g.positions() {
+		if ply > last {
+			break
+		}
+		if ply >= first && StructureSignature(position, material) == signature {
+			return true
+		}
+	}
+	return false
+}
+
+// StructuresIndex maps structure signatures, both with and
+// without material, to ids of the games reaching the structure
+type StructuresIndex map[uint64][]GameID
+
+func (i StructuresIndex) InsertFromChess(id GameID, game *chess.Game) {
+	for _, position := range game.Positions()[1:] { // skip the starting one
+		for _, material := range [...]bool{false, true} {
+			hash := StructureSignature(position, material)
+			// game positions are inserted in a row,
+			// so repetitions are at the end only
+			if ids := i[hash]; len(ids) == 0 || ids[len(ids)-1] != id {
+				i[hash] = append(ids, id)
+			}
+		}
+	}
+}
+
+func (i StructuresIndex) Merge(other StructuresIndex) {
+	for hash, ids := range other {
+		i[hash] = append(i[hash], ids...)
+	}
+}
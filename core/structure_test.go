@@ -0,0 +1,91 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestStructureSignature(t *testing.T) {
+	// isolated queen pawn, the pieces differ below
+	const iqp = "r1bq1rk1/pp2bppp/2n2n2/3p4/3P4/2NB1N2/PP3PPP/R1BQ1RK1"
+
+	tests := []struct {
+		name     string
+		a, b     string
+		same     bool
+		material bool
+	}{
+		{"same position", iqp + " w - - 0 10", iqp + " w - - 0 10", true, true},
+		{"side to move is ignored", iqp + " w - - 0 10", iqp + " b - - 0 10", true, true},
+		{
+			"pieces moved",
+			iqp + " w - - 0 10",
+			"r2q1rk1/pp2bppp/2n1bn2/3p4/3P4/2N2N2/PPB2PPP/R1BQ1RK1 w - - 0 11",
+			true, true,
+		},
+		{
+			"kings moved",
+			iqp + " w - - 0 10",
+			"r1bq1r1k/pp2bppp/2n2n2/3p4/3P4/2NB1N2/PP3PPP/R1BQ1R1K w - - 0 11",
+			true, true,
+		},
+		{
+			"pieces traded",
+			iqp + " w - - 0 10",
+			"r1bq1rk1/pp3ppp/2n2n2/3p4/3P4/2N2N2/PP3PPP/R1BQ1RK1 w - - 0 11",
+			true, false,
+		},
+		{
+			"pawn moved",
+			iqp + " w - - 0 10",
+			"r1bq1rk1/pp2bppp/2n2n2/3p4/3P4/P1NB1N2/1P3PPP/R1BQ1RK1 b - - 0 10",
+			false, false,
+		},
+		{
+			"pawn of another color",
+			"4k3/8/8/3p4/8/8/8/4K3 w - - 0 1",
+			"4k3/8/8/3P4/8/8/8/4K3 w - - 0 1",
+			false, false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := parseTestChessPosition(t, test.a), parseTestChessPosition(t, test.b)
+			if same := StructureSignature(a, false) == StructureSignature(b, false); same != test.same {
+				t.Errorf("same pawns signature is %v, want %v", same, test.same)
+			}
+			if same := StructureSignature(a, true) == StructureSignature(b, true); same != test.material {
+				t.Errorf("same material signature is %v, want %v", same, test.material)
+			}
+		})
+	}
+
+	t.Run("material differs from pawns only", func(t *testing.T) {
+		position := parseTestChessPosition(t, iqp+" w - - 0 10")
+		if StructureSignature(position, false) == StructureSignature(position, true) {
+			t.Errorf("material signature equals the pawns only one")
+		}
+	})
+}
+
+func TestStructuresIndexInsert(t *testing.T) {
+	index := make(StructuresIndex)
+	// knights shuffle keeps the structure, the game is listed once
+	game := parseTestChessGame(t, "1. e4 e5 2. Nf3 Nf6 3. Ng1 Ng8 4. Nf3")
+	index.InsertFromChess(ParseGameID("game0001"), game)
+	index.InsertFromChess(ParseGameID("game0002"), parseTestChessGame(t, "1. e4 e5 2. Nc3"))
+
+	position := parseTestChessPosition(t, "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2")
+	want := []GameID{ParseGameID("game0001"), ParseGameID("game0002")}
+	for _, material := range []bool{false, true} {
+		if ids := index[StructureSignature(position, material)]; !slices.Equal(ids, want) {
+			t.Errorf("games with material %v = %v, want %v", material, ids, want)
+		}
+	}
+
+	traded := parseTestChessPosition(t, "rnbqkb1r/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKB1R w KQkq - 0 2")
+	if ids := index[StructureSignature(traded, true)]; len(ids) != 0 {
+		t.Errorf("games with traded knights = %v, want none", ids)
+	}
+}
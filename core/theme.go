@@ -0,0 +1,174 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Theme uint8
+
+// themes are stored in the puzzles index as bit numbers,
+// so new ones must be appended to the end of the list
+var themeNames = [...]string{
+	"advancedPawn",
+	"advantage",
+	"anastasiaMate",
+	"arabianMate",
+	"attackingF2F7",
+	"attraction",
+	"backRankMate",
+	"balestraMate",
+	"bishopEndgame",
+	"blindSwineMate",
+	"bodenMate",
+	"capturingDefender",
+	"castling",
+	"clearance",
+	"cornerMate",
+	"crushing",
+	"defensiveMove",
+	"deflection",
+	"discoveredAttack",
+	"discoveredCheck",
+	"doubleBishopMate",
+	"doubleCheck",
+	"dovetailMate",
+	"enPassant",
+	"endgame",
+	"epauletteMate",
+	"equality",
+	"exposedKing",
+	"fork",
+	"hangingPiece",
+	"hookMate",
+	"interference",
+	"intermezzo",
+	"killBoxMate",
+	"kingsideAttack",
+	"knightEndgame",
+	"long",
+	"master",
+	"masterVsMaster",
+	"mate",
+	"mateIn1",
+	"mateIn2",
+	"mateIn3",
+	"mateIn4",
+	"mateIn5",
+	"middlegame",
+	"morphysMate",
+	"oneMove",
+	"opening",
+	"operaMate",
+	"pawnEndgame",
+	"pillsburysMate",
+	"pin",
+	"promotion",
+	"queenEndgame",
+	"queenRookEndgame",
+	"queensideAttack",
+	"quietMove",
+	"rookEndgame",
+	"sacrifice",
+	"short",
+	"skewer",
+	"smotheredMate",
+	"superGM",
+	"swallowstailMate",
+	"trappedPiece",
+	"triangleMate",
+	"underPromotion",
+	"veryLong",
+	"vukovicMate",
+	"xRayAttack",
+	"zugzwang",
+}
+
+var themesByName = func() map[string]Theme {
+	themes := make(map[string]Theme, len(themeNames))
+	for i, name := range themeNames {
+		themes[name] = Theme(i)
+	}
+	return themes
+}()
+
+func ParseTheme(s string) (t Theme, ok bool) {
+	t, ok = themesByName[s]
+	return
+}
+
+// ParseThemeNames is ParseThemes for user input,
+// which fails on unknown theme names
+func ParseThemeNames(names []string) (themes Themes, err error) {
+	for _, name := range names {
+		theme, ok := ParseTheme(name)
+		if !ok {
+			err = fmt.Errorf("unknown theme: %s", name)
+			return
+		}
+		themes = themes.With(theme)
+	}
+	return
+}
+
+func (t Theme) String() string {
+	return themeNames[t]
+}
+
+// Themes is a set of puzzle themes
+type Themes [2]uint64
+
+// ParseThemes reads space separated lichess theme names,
+// unknown themes are skipped
+func ParseThemes(s string) (themes Themes) {
+	for _, name := range strings.Fields(s) {
+		if theme, ok := ParseTheme(name); ok {
+			themes = themes.With(theme)
+		}
+	}
+	return
+}
+
+func (t Themes) With(theme Theme) Themes {
+	t[theme/64] |= 1 << (theme % 64)
+	return t
+}
+
+func (t Themes) Has(theme Theme) bool {
+	return t[theme/64]&(1<<(theme%64)) != 0
+}
+
+func (t Themes) Empty() bool {
+	return t[0] == 0 && t[1] == 0
+}
+
+// ContainsAll reports whether all the other themes are in the set
+func (t Themes) ContainsAll(other Themes) bool {
+	return t[0]&other[0] == other[0] && t[1]&other[1] == other[1]
+}
+
+// ContainsAny reports whether any of the other themes is in the set
+func (t Themes) ContainsAny(other Themes) bool {
+	return t[0]&other[0] != 0 || t[1]&other[1] != 0
+}
+
+func (t Themes) List() []Theme {
+	var themes []Theme
+	for i := range themeNames {
+		if t.Has(Theme(i)) {
+			themes = append(themes, Theme(i))
+		}
+	}
+	return themes
+}
+
+func (t Themes) String() string {
+	var s strings.Builder
+	for i, theme := range t.List() {
+		if i > 0 {
+			s.WriteRune(' ')
+		}
+		s.WriteString(theme.String())
+	}
+	return s.String()
+}
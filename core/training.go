@@ -0,0 +1,136 @@
+package core
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TrainingQueue schedules puzzles repetitions with SM-2 algorithm,
+// the longer puzzle is remembered the later it's repeated
+type TrainingQueue struct {
+	Cards map[PuzzleID]*TrainingCard `json:"cards"`
+}
+
+type TrainingCard struct {
+	Opening     string            `json:"opening"` // tag
+	Repetitions int               `json:"repetitions"`
+	Interval    int               `json:"interval_days"`
+	Ease        float64           `json:"ease"`
+	Due         time.Time         `json:"due"`
+	Attempts    []TrainingAttempt `json:"attempts"`
+}
+
+type TrainingAttempt struct {
+	Time   time.Time     `json:"time"`
+	Solved bool          `json:"solved"`
+	Took   time.Duration `json:"took"`
+}
+
+const (
+	InitialEase = 2.5
+	MinEase     = 1.3
+)
+
+// Grade rates the attempt from 0 to 5 as SM-2 expects,
+// solved puzzle is graded by how quickly it's solved
+func (a TrainingAttempt) Grade() int {
+	switch {
+	case !a.Solved:
+		return 1
+	case a.Took <= 15*time.Second:
+		return 5
+	case a.Took <= time.Minute:
+		return 4
+	default:
+		return 3
+	}
+}
+
+// Record adds the puzzle to the queue if it's not
+// there yet and schedules its next repetition
+func (q *TrainingQueue) Record(id PuzzleID, opening OpeningName, attempt TrainingAttempt) {
+	if q.Cards == nil {
+		q.Cards = make(map[PuzzleID]*TrainingCard)
+	}
+
+	card, ok := q.Cards[id]
+	if !ok {
+		card = &TrainingCard{Ease: InitialEase}
+		q.Cards[id] = card
+	}
+	if !opening.Empty() {
+		card.Opening = opening.Tag()
+	}
+
+	card.Attempts = append(card.Attempts, attempt)
+	card.schedule(attempt.Grade(), attempt.Time)
+}
+
+func (c *TrainingCard) schedule(grade int, now time.Time) {
+	if grade < 3 {
+		c.Repetitions = 0
+		c.Interval = 1
+	} else {
+		switch c.Repetitions {
+		case 0:
+			c.Interval = 1
+		case 1:
+			c.Interval = 6
+		default:
+			c.Interval = int(math.Round(float64(c.Interval) * c.Ease))
+		}
+		c.Repetitions++
+	}
+
+	miss := float64(5 - grade)
+	c.Ease = max(c.Ease+0.1-miss*(0.08+miss*0.02), MinEase)
+	c.Due = now.AddDate(0, 0, c.Interval)
+}
+
+// Due returns puzzles to repeat, the current opening
+// ones go first, then the ones of its family and the rest
+func (q *TrainingQueue) Due(now time.Time, opening OpeningName) []PuzzleID {
+	type due struct {
+		id   PuzzleID
+		rank int
+		card *TrainingCard
+	}
+
+	tag, family := opening.Tag(), opening.FamilyTag()
+	rank := func(card *TrainingCard) int {
+		switch {
+		case opening.Empty():
+			return 0
+		case card.Opening == tag:
+			return 0
+		case strings.HasPrefix(card.Opening, family):
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	cards := make([]due, 0, len(q.Cards))
+	for id, card := range q.Cards {
+		if !card.Due.After(now) {
+			cards = append(cards, due{id: id, rank: rank(card), card: card})
+		}
+	}
+
+	slices.SortFunc(cards, func(a, b due) int {
+		return cmp.Or(
+			cmp.Compare(a.rank, b.rank),
+			a.card.Due.Compare(b.card.Due),
+			strings.Compare(a.id.String(), b.id.String()),
+		)
+	})
+
+	ids := make([]PuzzleID, len(cards))
+	for i, card := range cards {
+		ids[i] = card.id
+	}
+	return ids
+}
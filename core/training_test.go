@@ -0,0 +1,110 @@
+package core
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTrainingAttemptGrade(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt TrainingAttempt
+		want    int
+	}{
+		{"failed", TrainingAttempt{Solved: false, Took: time.Second}, 1},
+		{"quickly solved", TrainingAttempt{Solved: true, Took: 15 * time.Second}, 5},
+		{"solved in a minute", TrainingAttempt{Solved: true, Took: time.Minute}, 4},
+		{"slowly solved", TrainingAttempt{Solved: true, Took: 2 * time.Minute}, 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.attempt.Grade(); got != test.want {
+				t.Errorf("Grade() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTrainingQueueRecord(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	id := ParsePuzzleID("00001")
+	opening := ParseOpeningName("B90", "Sicilian Defense: Najdorf Variation")
+
+	// attempts are recorded one after another on the same card
+	tests := []struct {
+		name        string
+		solved      bool
+		took        time.Duration
+		repetitions int
+		interval    int
+		ease        float64
+	}{
+		{"first repetition", true, 10 * time.Second, 1, 1, 2.6},
+		{"second repetition", true, 10 * time.Second, 2, 6, 2.7},
+		{"interval grows by ease", true, 10 * time.Second, 3, 16, 2.8},
+		{"failure starts over", false, 10 * time.Second, 0, 1, 2.26},
+		{"slow solve lowers ease", true, 2 * time.Minute, 1, 1, 2.12},
+	}
+
+	var queue TrainingQueue
+	now := start
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queue.Record(id, opening, TrainingAttempt{Time: now, Solved: test.solved, Took: test.took})
+
+			card := queue.Cards[id]
+			if card.Repetitions != test.repetitions || card.Interval != test.interval || math.Abs(card.Ease-test.ease) > 1e-9 {
+				t.Errorf("card = %d repetitions, %d days, ease %.2f, want %d, %d, %.2f",
+					card.Repetitions, card.Interval, card.Ease, test.repetitions, test.interval, test.ease)
+			}
+			if want := now.AddDate(0, 0, test.interval); !card.Due.Equal(want) {
+				t.Errorf("due %v, want %v", card.Due, want)
+			}
+			if card.Opening != opening.Tag() {
+				t.Errorf("opening = %s, want %s", card.Opening, opening.Tag())
+			}
+			now = card.Due
+		})
+	}
+
+	if len(queue.Cards[id].Attempts) != len(tests) {
+		t.Errorf("%d attempts recorded, want %d", len(queue.Cards[id].Attempts), len(tests))
+	}
+}
+
+func TestTrainingQueueDue(t *testing.T) {
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	opening := ParseOpeningName("B90", "Sicilian Defense: Najdorf Variation")
+
+	queue := TrainingQueue{Cards: map[PuzzleID]*TrainingCard{
+		ParsePuzzleID("other"): {Opening: "French_Defense", Due: now.AddDate(0, 0, -3)},
+		ParsePuzzleID("famil"): {Opening: "Sicilian_Defense_Dragon_Variation", Due: now.AddDate(0, 0, -2)},
+		ParsePuzzleID("same1"): {Opening: opening.Tag(), Due: now},
+		ParsePuzzleID("same2"): {Opening: opening.Tag(), Due: now.AddDate(0, 0, -1)},
+		ParsePuzzleID("later"): {Opening: opening.Tag(), Due: now.AddDate(0, 0, 1)},
+	}}
+
+	tests := []struct {
+		name    string
+		opening OpeningName
+		want    []string
+	}{
+		{"current opening first", opening, []string{"same2", "same1", "famil", "other"}},
+		{"by due without opening", OpeningName{}, []string{"other", "famil", "same2", "same1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, id := range queue.Due(now, test.opening) {
+				got = append(got, id.String())
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("Due() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
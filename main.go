@@ -8,13 +8,11 @@ import (
 	"runtime/debug"
 	"syscall"
 
+	"github.com/failosof/cops/cli"
 	"github.com/failosof/cops/ui"
 )
 
-// todo: pagination
-
 // todo: search options: by moves sequence or by position
-// todo: cache games with position hashes
 
 const MemLimit = 4 * 1024 * 1024 * 1024 // 4 Gb
 
@@ -26,6 +24,14 @@ func main() {
 	debug.SetMemoryLimit(MemLimit)
 	debug.SetGCPercent(-1)
 
+	if args := os.Args[1:]; cli.IsCommand(args) {
+		if err := cli.Run(ctx, args); err != nil {
+			slog.Error("failed to run command", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	window, err := ui.NewWindow()
 	if err != nil {
 		slog.Error("failed to create main window", "err", err)
@@ -0,0 +1,110 @@
+package resources
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const AppName = "cops"
+
+// ConfigDir returns the application directory inside
+// the user config one, it's created if doesn't exist
+func ConfigDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user config dir: %w", err)
+	}
+
+	dir = filepath.Join(dir, AppName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create config dir %s: %w", dir, err)
+	}
+
+	return dir, nil
+}
+
+// LoadConfig reads json file from the config dir,
+// the zero value is returned if there is no file yet
+func LoadConfig[T any](filename string) (t T, err error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return
+	}
+
+	filename = filepath.Join(dir, filename)
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to read config file %s: %w", filename, err)
+		return
+	}
+
+	if err = json.Unmarshal(data, &t); err != nil {
+		err = fmt.Errorf("failed to parse config file %s: %w", filename, err)
+	}
+
+	return
+}
+
+// SaveConfig writes json file to the config dir, the file
+// is replaced at once, so it's never left half-written
+func SaveConfig[T any](filename string, t T) error {
+	dir, err := ConfigDir()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode config %s: %w", filename, err)
+	}
+
+	filename = filepath.Join(dir, filename)
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return fmt.Errorf("failed to create config dir for %s: %w", filename, err)
+	}
+
+	temp := filename + ".tmp"
+	if err := os.WriteFile(temp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write config file %s: %w", temp, err)
+	}
+	if err := os.Rename(temp, filename); err != nil {
+		return fmt.Errorf("failed to replace config file %s: %w", filename, err)
+	}
+
+	return nil
+}
+
+// ListConfigs returns names of the files in the config
+// subdirectory, which have the extension
+func ListConfigs(subdir, ext string) ([]string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	dir = filepath.Join(dir, subdir)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config dir %s: %w", dir, err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && filepath.Ext(entry.Name()) == ext {
+			names = append(names, entry.Name())
+		}
+	}
+
+	return names, nil
+}
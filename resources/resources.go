@@ -3,6 +3,7 @@ package resources
 import (
 	"embed"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"log/slog"
 	"path/filepath"
@@ -18,7 +19,11 @@ var Indexes embed.FS
 //go:embed assets
 var Assets embed.FS
 
-func LoadIndex[I any](filename string) (i I, err error) {
+var ErrIndexVersion = errors.New("unsupported index version")
+
+// LoadIndex reads index file, which starts with
+// format version followed by the index itself
+func LoadIndex[I any](filename string, version uint32) (i I, err error) {
 	file, err := Indexes.Open(filename)
 	if err != nil {
 		err = fmt.Errorf("failed to open index file %s: %w", filename, err)
@@ -26,8 +31,18 @@ func LoadIndex[I any](filename string) (i I, err error) {
 	}
 	defer file.Close()
 
-	if err = gob.NewDecoder(file).Decode(&i); err != nil {
-		err = fmt.Errorf("failed to read binary file %s: %w", err)
+	decoder := gob.NewDecoder(file)
+
+	var fileVersion uint32
+	if err = decoder.Decode(&fileVersion); err != nil || fileVersion != version {
+		// indexes created before versioning
+		// start with the index map itself
+		err = fmt.Errorf("index file %s (want version %d, have %d): %w", filename, version, fileVersion, ErrIndexVersion)
+		return
+	}
+
+	if err = decoder.Decode(&i); err != nil {
+		err = fmt.Errorf("failed to read binary file %s: %w", filename, err)
 		return
 	}
 
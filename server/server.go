@@ -0,0 +1,296 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/failosof/cops/core"
+	"github.com/notnil/chess"
+)
+
+const (
+	DefaultLimit   = 30 // same as the window page size
+	MaxLimit       = 1000
+	DefaultTimeout = 30 * time.Second
+	MaxMoves       = 40 // same limits as in the cli
+	MaxTolerance   = 40
+	maxRequestSize = 64 * 1024
+)
+
+type Server struct {
+	index   *core.Index
+	timeout time.Duration
+	mux     *http.ServeMux
+}
+
+func New(index *core.Index, timeout time.Duration) *Server {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	s := &Server{
+		index:   index,
+		timeout: timeout,
+		mux:     http.NewServeMux(),
+	}
+	s.mux.HandleFunc("POST /openings/lookup", s.handleOpeningLookup)
+	s.mux.HandleFunc("POST /puzzles/search", s.handlePuzzlesSearch)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+type OpeningLookupRequest struct {
+	Moves string `json:"moves"`
+}
+
+type OpeningLookupResponse struct {
+	ECO        string   `json:"eco"`
+	Name       string   `json:"name"`
+	Family     string   `json:"family"`
+	Variation  string   `json:"variation"`
+	Variations []string `json:"variations"`
+	Tag        string   `json:"tag"`
+	Leftover   []string `json:"leftover"`
+}
+
+func (s *Server) handleOpeningLookup(w http.ResponseWriter, r *http.Request) {
+	var req OpeningLookupRequest
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	game, err := parseMoves(req.Moves)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	opening, leftover := s.index.SearchOpening(game)
+	if opening.Empty() {
+		writeError(w, http.StatusNotFound, errors.New("opening not found"))
+		return
+	}
+
+	resp := OpeningLookupResponse{
+		ECO:        opening.ECO,
+		Name:       opening.String(),
+		Family:     opening.Family(),
+		Variation:  opening.Variation(),
+		Variations: append([]string{}, opening.Variations()...),
+		Tag:        opening.Tag(),
+		Leftover:   make([]string, len(leftover)),
+	}
+	for i, move := range leftover {
+		resp.Leftover[i] = move.String()
+	}
+	writeResponse(w, http.StatusOK, resp)
+}
+
+type PuzzlesSearchRequest struct {
+	Moves          string   `json:"moves"`
+	FEN            string   `json:"fen"`
+	Tags           []string `json:"tags"`
+	Family         string   `json:"family"`
+	ECO            string   `json:"eco"`
+	Pattern        string   `json:"pattern"`
+	Material       bool     `json:"material"`
+	Board          string   `json:"board"`
+	Type           string   `json:"type"`
+	Tolerance      uint8    `json:"tolerance"`
+	Turn           string   `json:"turn"`
+	MaxMoves       uint8    `json:"max_moves"`
+	MinRating      uint16   `json:"min_rating"`
+	MaxRating      uint16   `json:"max_rating"`
+	MinPopularity  *int8    `json:"min_popularity"`
+	Themes         []string `json:"themes"`
+	ExcludedThemes []string `json:"excluded_themes"`
+	Sort           string   `json:"sort"`
+	Offset         int      `json:"offset"`
+	Limit          int      `json:"limit"`
+}
+
+type PuzzlesSearchResponse struct {
+	Total   int               `json:"total"`
+	Offset  int               `json:"offset"`
+	Limit   int               `json:"limit"`
+	Puzzles []core.PuzzleData `json:"puzzles"`
+}
+
+func (s *Server) handlePuzzlesSearch(w http.ResponseWriter, r *http.Request) {
+	req := PuzzlesSearchRequest{
+		Type:     "moves",
+		MaxMoves: 10,
+		Limit:    DefaultLimit,
+	}
+	if err := decodeRequest(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	strategy, err := core.ParseSearchType(req.Type)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	turn, err := core.ParseTurn(req.Turn)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	themes, err := core.ParseThemeNames(req.Themes)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	excludedThemes, err := core.ParseThemeNames(req.ExcludedThemes)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	sort, err := core.ParseSortOrder(req.Sort)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.MaxMoves < 1 || MaxMoves < req.MaxMoves {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid max moves: %d", req.MaxMoves))
+		return
+	}
+	if popularity := req.MinPopularity; popularity != nil && (*popularity < -100 || 100 < *popularity) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid min popularity: %d", *popularity))
+		return
+	}
+	if MaxTolerance < req.Tolerance {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid tolerance: %d", req.Tolerance))
+		return
+	}
+	if req.Offset < 0 || req.Limit < 1 || MaxLimit < req.Limit {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid pagination: offset %d, limit %d", req.Offset, req.Limit))
+		return
+	}
+
+	query := core.Query{
+		Strategy:  strategy,
+		Tolerance: req.Tolerance,
+		Material:  req.Material,
+		PuzzleFilter: core.PuzzleFilter{
+			Turn:           turn.ToChess(),
+			MaxMoves:       req.MaxMoves,
+			MinRating:      req.MinRating,
+			MaxRating:      req.MaxRating,
+			MinPopularity:  req.MinPopularity,
+			Themes:         themes,
+			ExcludedThemes: excludedThemes,
+		},
+		Sort: sort,
+	}
+	switch {
+	case strategy == core.BoardSearch:
+		if query.Board, err = core.ParseBoardPattern(req.Board); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	case strategy == core.PatternSearch:
+		if query.Pattern, err = core.ParseMovePattern(req.Pattern); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	case len(req.Tags) > 0 || len(req.Family) > 0 || len(req.ECO) > 0:
+		if len(req.Moves) > 0 || len(req.FEN) > 0 {
+			writeError(w, http.StatusBadRequest, errors.New("tags, family and eco can't be searched together with moves or fen"))
+			return
+		}
+		query.Tags = req.Tags
+		query.Family = req.Family
+		if len(req.ECO) > 0 {
+			if query.ECO, err = core.ParseECORange(req.ECO); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+	case len(req.FEN) > 0:
+		if strategy == core.MoveSequenceSearch {
+			writeError(w, http.StatusBadRequest, errors.New("fen can't be searched by moves"))
+			return
+		}
+		if query.Position, err = core.ParseFEN(req.FEN); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		if query.Game, err = parseMoves(req.Moves); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err = query.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+
+	results, err := s.index.CollectPuzzles(ctx, query)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	resp := PuzzlesSearchResponse{
+		Total:   len(results),
+		Offset:  req.Offset,
+		Limit:   req.Limit,
+		Puzzles: paginate(results, req.Offset, req.Limit),
+	}
+	writeResponse(w, http.StatusOK, resp)
+}
+
+func paginate(results []core.PuzzleData, offset, limit int) []core.PuzzleData {
+	if offset >= len(results) {
+		return []core.PuzzleData{}
+	}
+	return results[offset:min(offset+limit, len(results))]
+}
+
+func parseMoves(moves string) (*chess.Game, error) {
+	game, err := core.ParsePGN(moves)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse moves: %w", err)
+	}
+	return game, nil
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, req any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	return nil
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeResponse(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Warn("failed to write response", "err", err)
+	}
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/failosof/cops/core"
+	"github.com/notnil/chess"
+)
+
+const italianGame = "1. e4 e5 2. Nf3 Nc6 3. Bc4"
+
+func TestOpeningLookup(t *testing.T) {
+	srv := New(newTestIndex(t, 0), 0)
+
+	tests := []struct {
+		name     string
+		body     string
+		status   int
+		opening  string
+		leftover []string
+	}{
+		{"book line", `{"moves": "` + italianGame + `"}`, http.StatusOK, "Italian Game", []string{}},
+		{"moves after the book line", `{"moves": "` + italianGame + ` Bc5"}`, http.StatusOK, "Italian Game", []string{"f8c5"}},
+		{"out of book", `{"moves": "1. a3"}`, http.StatusNotFound, "", nil},
+		{"illegal moves", `{"moves": "1. e5"}`, http.StatusBadRequest, "", nil},
+		{"bad json", `{"moves": `, http.StatusBadRequest, "", nil},
+		{"unknown field", `{"moves": "1. e4", "fen": ""}`, http.StatusBadRequest, "", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := serve(srv, httptest.NewRequest(http.MethodPost, "/openings/lookup", strings.NewReader(test.body)))
+			if resp.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, test.status, resp.Body)
+			}
+			if test.status != http.StatusOK {
+				assertError(t, resp)
+				return
+			}
+
+			var lookup OpeningLookupResponse
+			if err := json.NewDecoder(resp.Body).Decode(&lookup); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if lookup.Name != test.opening || lookup.Tag != "Italian_Game" || lookup.ECO != "C50" {
+				t.Errorf("opening = %+v, want %s", lookup, test.opening)
+			}
+			if fmt.Sprint(lookup.Leftover) != fmt.Sprint(test.leftover) {
+				t.Errorf("leftover = %v, want %v", lookup.Leftover, test.leftover)
+			}
+		})
+	}
+}
+
+func TestPuzzlesSearch(t *testing.T) {
+	const puzzles = 5
+	srv := New(newTestIndex(t, puzzles), 0)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		total  int
+		found  int
+	}{
+		{"defaults", `{"moves": "` + italianGame + `"}`, http.StatusOK, puzzles, puzzles},
+		{"first page", `{"moves": "` + italianGame + `", "limit": 2}`, http.StatusOK, puzzles, 2},
+		{"last page", `{"moves": "` + italianGame + `", "offset": 4, "limit": 2}`, http.StatusOK, puzzles, 1},
+		{"offset past the results", `{"moves": "` + italianGame + `", "offset": 10}`, http.StatusOK, puzzles, 0},
+		{"negative offset", `{"moves": "` + italianGame + `", "offset": -1}`, http.StatusBadRequest, 0, 0},
+		{"zero limit", `{"moves": "` + italianGame + `", "limit": 0}`, http.StatusBadRequest, 0, 0},
+		{"limit over max", fmt.Sprintf(`{"moves": "%s", "limit": %d}`, italianGame, MaxLimit+1), http.StatusBadRequest, 0, 0},
+		{"zero max moves", `{"moves": "` + italianGame + `", "max_moves": 0}`, http.StatusBadRequest, 0, 0},
+		{"max moves over limit", `{"moves": "` + italianGame + `", "max_moves": 41}`, http.StatusBadRequest, 0, 0},
+		{"max moves out of type range", `{"moves": "` + italianGame + `", "max_moves": 300}`, http.StatusBadRequest, 0, 0},
+		{"popularity over limit", `{"moves": "` + italianGame + `", "min_popularity": 101}`, http.StatusBadRequest, 0, 0},
+		{"zero popularity", `{"moves": "` + italianGame + `", "min_popularity": 0}`, http.StatusOK, puzzles, puzzles},
+		{"tolerance over limit", `{"moves": "` + italianGame + `", "tolerance": 41}`, http.StatusBadRequest, 0, 0},
+		{"bad json", `{"moves": "` + italianGame, http.StatusBadRequest, 0, 0},
+		{"unknown field", `{"moves": "` + italianGame + `", "depth": 3}`, http.StatusBadRequest, 0, 0},
+		{"invalid type", `{"moves": "` + italianGame + `", "type": "random"}`, http.StatusBadRequest, 0, 0},
+		{"fen searched by moves", `{"fen": "8/8/8/8/8/8/8/8 w - - 0 1"}`, http.StatusBadRequest, 0, 0},
+		{"no moves", `{}`, http.StatusBadRequest, 0, 0},
+		{"empty pattern", `{"type": "pattern", "pattern": ""}`, http.StatusBadRequest, 0, 0},
+		{"empty board", `{"type": "board", "board": ""}`, http.StatusBadRequest, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := serve(srv, httptest.NewRequest(http.MethodPost, "/puzzles/search", strings.NewReader(test.body)))
+			if resp.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", resp.Code, test.status, resp.Body)
+			}
+			if test.status != http.StatusOK {
+				assertError(t, resp)
+				return
+			}
+
+			var search struct {
+				Total   int               `json:"total"`
+				Puzzles []json.RawMessage `json:"puzzles"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&search); err != nil {
+				t.Fatalf("invalid response: %v", err)
+			}
+			if search.Total != test.total || len(search.Puzzles) != test.found {
+				t.Errorf("found %d of %d puzzles, want %d of %d", len(search.Puzzles), search.Total, test.found, test.total)
+			}
+		})
+	}
+}
+
+func TestPuzzlesSearchAborted(t *testing.T) {
+	index := newTestIndex(t, 5)
+	body := `{"moves": "` + italianGame + `"}`
+
+	t.Run("timeout", func(t *testing.T) {
+		srv := New(index, time.Nanosecond)
+		resp := serve(srv, httptest.NewRequest(http.MethodPost, "/puzzles/search", strings.NewReader(body)))
+		if resp.Code != http.StatusServiceUnavailable {
+			t.Fatalf("status = %d, want %d: %s", resp.Code, http.StatusServiceUnavailable, resp.Body)
+		}
+		assertError(t, resp)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel() // client is gone
+		srv := New(index, time.Minute)
+		req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/puzzles/search", strings.NewReader(body))
+		resp := serve(srv, req)
+		if resp.Code != http.StatusServiceUnavailable {
+			t.Fatalf("status = %d, want %d: %s", resp.Code, http.StatusServiceUnavailable, resp.Body)
+		}
+		assertError(t, resp)
+	})
+}
+
+func TestUnknownRoute(t *testing.T) {
+	srv := New(newTestIndex(t, 0), 0)
+	if resp := serve(srv, httptest.NewRequest(http.MethodGet, "/puzzles/search", nil)); resp.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", resp.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func serve(srv *Server, req *http.Request) *httptest.ResponseRecorder {
+	resp := httptest.NewRecorder()
+	srv.ServeHTTP(resp, req)
+	return resp
+}
+
+func assertError(t *testing.T, resp *httptest.ResponseRecorder) {
+	t.Helper()
+	var body errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || len(body.Error) == 0 {
+		t.Errorf("error response expected, got %v", err)
+	}
+}
+
+// newTestIndex indexes the Italian Game with the
+// puzzles from the games following its book line
+func newTestIndex(t *testing.T, puzzles int) *core.Index {
+	t.Helper()
+
+	index := &core.Index{
+		Openings:  make(core.OpeningsIndex),
+		Games:     make(core.GamesIndex),
+		Positions: make(core.PositionsIndex),
+		Puzzles:   make(core.PuzzlesIndex),
+	}
+	if err := index.Openings.Insert("C50", "Italian Game", italianGame); err != nil {
+		t.Fatalf("invalid test opening: %v", err)
+	}
+
+	game, err := core.ParsePGN(italianGame + " Bc5 4. c3 Nf6 5. d4 exd4 6. cxd4 Bb4+")
+	if err != nil {
+		t.Fatalf("invalid test game: %v", err)
+	}
+	for i := range puzzles {
+		id := core.ParseGameID(fmt.Sprintf("game%04d", i))
+		index.Games.InsertFromChess(id, game)
+		index.Positions.InsertFromChess(id, game)
+		index.Puzzles["Italian_Game"] = append(index.Puzzles["Italian_Game"], core.PuzzleData{
+			Move:   7,
+			Turn:   chess.White,
+			ID:     core.ParsePuzzleID(fmt.Sprintf("pzl%02d", i)),
+			GameID: id,
+			Rating: 1500,
+		})
+	}
+	index.Positions.Sort()
+
+	return index
+}
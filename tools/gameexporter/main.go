@@ -17,36 +17,75 @@ import (
 const MemoryLimit int64 = 10 * 1024 * 1024 * 1024 // 10 Gb
 
 func main() {
-	if len(os.Args) < 3 {
-		log.Fatalf("Usage: gameexporter <puzzles.index> <games.index>")
+	if len(os.Args) < 5 {
+		log.Fatalf("Usage: gameexporter <puzzles.index> <games.index> <positions.index> <structures.index>")
 	}
 
 	debug.SetGCPercent(-1)
 	debug.SetMemoryLimit(MemoryLimit)
 
-	puzzleIndexFile, gameIndexFile := os.Args[1], os.Args[2]
+	puzzleIndexFile, gameIndexFile, positionIndexFile, structureIndexFile := os.Args[1], os.Args[2], os.Args[3], os.Args[4]
 
 	puzzles, err := LoadPuzzlesIndex(puzzleIndexFile)
 	if err != nil {
 		log.Fatalf("Failed to load puzzles index: %v", err)
 	}
 
-	filename := "games.index.extended"
 	games, err := LoadGamesIndex(gameIndexFile)
 	if err != nil {
 		log.Fatalf("Failed to load games index: %v", err)
 	}
-	defer util.SaveBinary(filename, games)
+
+	positions, err := LoadPositionsIndex(positionIndexFile)
+	if err != nil {
+		log.Fatalf("Failed to load positions index: %v", err)
+	}
+
+	structures, err := LoadStructuresIndex(structureIndexFile)
+	if err != nil {
+		log.Fatalf("Failed to load structures index: %v", err)
+	}
 
 	log.Printf("Starting games export from %d", len(games))
-	ExportGames(context.Background(), puzzles, games, filename)
+	ExportGames(context.Background(), puzzles, games, positions, structures)
+
+	if err := SaveIndexes(games, positions, structures); err != nil {
+		log.Fatalf("Failed to save indexes: %v", err)
+	}
 
-	filename, _ = filepath.Abs(filename)
-	log.Printf("Saved to %q", filename)
+	for _, filename := range [...]string{ExtendedGamesFile, ExtendedPositionsFile, ExtendedStructuresFile} {
+		filename, _ = filepath.Abs(filename)
+		log.Printf("Saved to %q", filename)
+	}
+}
+
+const (
+	ExtendedGamesFile      = "games.index.extended"
+	ExtendedPositionsFile  = "positions.index.extended"
+	ExtendedStructuresFile = "structures.index.extended"
+)
+
+// SaveIndexes writes the extended indexes together, so the games saved
+// are position and structure indexed if the export is interrupted
+func SaveIndexes(games core.GamesIndex, positions core.PositionsIndex, structures core.StructuresIndex) error {
+	if err := util.SaveIndex(ExtendedGamesFile, core.IndexVersion, games); err != nil {
+		return fmt.Errorf("failed to save games: %w", err)
+	}
+
+	positions.Sort()
+	if err := util.SaveIndex(ExtendedPositionsFile, core.IndexVersion, positions); err != nil {
+		return fmt.Errorf("failed to save positions: %w", err)
+	}
+
+	if err := util.SaveIndex(ExtendedStructuresFile, core.IndexVersion, structures); err != nil {
+		return fmt.Errorf("failed to save structures: %w", err)
+	}
+
+	return nil
 }
 
 func LoadPuzzlesIndex(filename string) (core.PuzzlesIndex, error) {
-	puzzles, err := util.LoadBinary[core.PuzzlesIndex](filename)
+	puzzles, err := util.LoadIndex[core.PuzzlesIndex](filename, core.IndexVersion)
 	if err != nil {
 		log.Println("failed to load puzzles index")
 		return nil, err
@@ -56,7 +95,7 @@ func LoadPuzzlesIndex(filename string) (core.PuzzlesIndex, error) {
 }
 
 func LoadGamesIndex(filename string) (core.GamesIndex, error) {
-	games, err := util.LoadBinary[core.GamesIndex](filename)
+	games, err := util.LoadIndex[core.GamesIndex](filename, core.IndexVersion)
 	if err != nil {
 		log.Println("failed to load games index")
 		return nil, err
@@ -65,7 +104,33 @@ func LoadGamesIndex(filename string) (core.GamesIndex, error) {
 	return games, nil
 }
 
-func ExportGames(ctx context.Context, puzzles core.PuzzlesIndex, gamesIndex core.GamesIndex, filename string) {
+func LoadPositionsIndex(filename string) (core.PositionsIndex, error) {
+	positions, err := util.LoadIndex[core.PositionsIndex](filename, core.IndexVersion)
+	if err != nil {
+		log.Println("failed to load positions index")
+		return nil, err
+	}
+	log.Printf("loaded %d positions from %q", len(positions), filename)
+	return positions, nil
+}
+
+func LoadStructuresIndex(filename string) (core.StructuresIndex, error) {
+	structures, err := util.LoadIndex[core.StructuresIndex](filename, core.IndexVersion)
+	if err != nil {
+		log.Println("failed to load structures index")
+		return nil, err
+	}
+	log.Printf("loaded %d structures from %q", len(structures), filename)
+	return structures, nil
+}
+
+func ExportGames(
+	ctx context.Context,
+	puzzles core.PuzzlesIndex,
+	gamesIndex core.GamesIndex,
+	positionsIndex core.PositionsIndex,
+	structuresIndex core.StructuresIndex,
+) {
 	log.Println("Collecting unexported games ...")
 	toExport := make([]string, 0, len(gamesIndex))
 	for _, puzzleCollection := range puzzles {
@@ -98,11 +163,13 @@ func ExportGames(ctx context.Context, puzzles core.PuzzlesIndex, gamesIndex core
 		for _, game := range games {
 			exportedGameID := core.ParseGameID(game.GetTagPair("GameId").Value)
 			gamesIndex.InsertFromChess(exportedGameID, game)
+			positionsIndex.InsertFromChess(exportedGameID, game)
+			structuresIndex.InsertFromChess(exportedGameID, game)
 		}
 
-		if err := util.SaveBinary(filename, gamesIndex); err != nil {
+		if err := SaveIndexes(gamesIndex, positionsIndex, structuresIndex); err != nil {
 			fmt.Println()
-			log.Printf("failed to save games: %v", err)
+			log.Printf("failed to save indexes: %v", err)
 			fail = true
 		}
 
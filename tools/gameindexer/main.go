@@ -16,6 +16,7 @@ import (
 	"github.com/failosof/cops/core"
 	"github.com/failosof/cops/tools/util"
 	"github.com/goccy/go-json"
+	"github.com/notnil/chess"
 	"golang.org/x/exp/mmap"
 )
 
@@ -49,46 +50,66 @@ func main() {
 	filenames := os.Args[1:]
 
 	log.Printf("Indexing games from %s ...", strings.Join(filenames, ", "))
-	index, err := CreateGamesIndex(filenames)
+	index, positions, structures, err := CreateGamesIndex(filenames)
 	if err != nil {
 		log.Fatalf("Failed to create games index: %v", err)
 	}
 
-	log.Println("Saving file ...")
+	log.Println("Saving files ...")
 	file := "games.index"
-	if err := util.SaveBinary(file, &index); err != nil {
+	if err := util.SaveIndex(file, core.IndexVersion, &index); err != nil {
 		log.Fatalf("Failed to save games index: %v", err)
 	}
 
 	log.Printf("Index of %d games created in %q\n", len(index), file)
+
+	file = "positions.index"
+	if err := util.SaveIndex(file, core.IndexVersion, &positions); err != nil {
+		log.Fatalf("Failed to save positions index: %v", err)
+	}
+
+	log.Printf("Index of %d positions created in %q\n", len(positions), file)
+
+	file = "structures.index"
+	if err := util.SaveIndex(file, core.IndexVersion, &structures); err != nil {
+		log.Fatalf("Failed to save structures index: %v", err)
+	}
+
+	log.Printf("Index of %d structures created in %q\n", len(structures), file)
 }
 
 type fileResult struct {
-	index core.GamesIndex
-	err   error
+	index      core.GamesIndex
+	positions  core.PositionsIndex
+	structures core.StructuresIndex
+	err        error
 }
 
-func CreateGamesIndex(filenames []string) (core.GamesIndex, error) {
+func CreateGamesIndex(filenames []string) (core.GamesIndex, core.PositionsIndex, core.StructuresIndex, error) {
 	resChan := make(chan fileResult, len(filenames))
 	defer close(resChan)
 
 	for _, filename := range filenames {
 		go func(filename string) {
-			idx, err := processFile(filename)
-			resChan <- fileResult{index: idx, err: err}
+			idx, positions, structures, err := processFile(filename)
+			resChan <- fileResult{index: idx, positions: positions, structures: structures, err: err}
 		}(filename)
 	}
 
 	index := make(core.GamesIndex, AssumedGameCount)
+	positions := make(core.PositionsIndex, AssumedGameCount)
+	structures := make(core.StructuresIndex, AssumedGameCount)
 	workers := len(filenames)
 loop:
 	for {
 		select {
 		case res := <-resChan:
 			if res.err != nil {
-				return nil, res.err
+				return nil, nil, nil, res.err
 			}
 			maps.Copy(index, res.index)
+			positions.Merge(res.positions)
+			structures.Merge(res.structures)
 			workers--
 			if workers == 0 {
 				break loop
@@ -100,17 +121,21 @@ loop:
 
 	fmt.Printf("\rIndexed %d games out of %d records\n", indexed.Load(), processed.Load())
 
-	return index, nil
+	positions.Sort()
+
+	return index, positions, structures, nil
 }
 
-func processFile(filename string) (core.GamesIndex, error) {
+func processFile(filename string) (core.GamesIndex, core.PositionsIndex, core.StructuresIndex, error) {
 	file, err := mmap.Open(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file %q: %w", filename, err)
+		return nil, nil, nil, fmt.Errorf("failed to open file %q: %w", filename, err)
 	}
 	defer file.Close()
 
 	index := make(core.GamesIndex, FileRecords)
+	positions := make(core.PositionsIndex, FileRecords)
+	structures := make(core.StructuresIndex, FileRecords)
 
 	sr := io.NewSectionReader(file, 0, int64(file.Len()))
 	decoder := json.NewDecoder(sr)
@@ -120,18 +145,24 @@ func processFile(filename string) (core.GamesIndex, error) {
 			if errors.Is(err, io.EOF) {
 				break
 			}
-			return nil, fmt.Errorf("failed to decode record: %w", err)
+			return nil, nil, nil, fmt.Errorf("failed to decode record: %w", err)
 		}
 
 		if len(record.Puzzle.OpeningFamily) > 0 {
-			if err := index.Insert(record.Game.ID, record.Game.Moves); err != nil {
-				return nil, fmt.Errorf("failed to index game %s: %w", record.Game.ID, err)
+			pgn, err := chess.PGN(strings.NewReader(record.Game.Moves))
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("failed to index game %s: %w", record.Game.ID, err)
 			}
+			game := chess.NewGame(pgn)
+			id := core.ParseGameID(record.Game.ID)
+			index.InsertFromChess(id, game)
+			positions.InsertFromChess(id, game)
+			structures.InsertFromChess(id, game)
 			indexed.Add(1)
 		}
 
 		processed.Add(1)
 	}
 
-	return index, nil
+	return index, positions, structures, nil
 }
@@ -37,7 +37,7 @@ func main() {
 	}
 
 	filename := "openings.index"
-	if err := util.SaveBinary(filename, index); err != nil {
+	if err := util.SaveIndex(filename, core.IndexVersion, index); err != nil {
 		log.Fatalf("Failed to save openings index: %v", err)
 	}
 
@@ -93,7 +93,7 @@ func parseFile(filename string, index core.OpeningsIndex) (n int, err error) {
 				err = fmt.Errorf("file %q line %d: want 3 fields, have %d", filename, lineNum, len(line))
 				return
 			}
-			if err = index.Insert(line[1], line[2]); err != nil {
+			if err = index.Insert(line[0], line[1], line[2]); err != nil {
 				return
 			}
 			n++
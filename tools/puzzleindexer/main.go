@@ -37,34 +37,43 @@ func main() {
 	}
 
 	log.Println("Indexing opening puzzles ...")
-	index, err := CreatePuzzlesIndex(filename)
+	index, solutions, err := CreatePuzzlesIndex(filename)
 	if err != nil {
 		log.Fatalf("Failed to create puzzles index: %v", err)
 	}
 
 	log.Println("Saving index ...")
 	filename = "puzzles.index"
-	if err := util.SaveBinary(filename, index); err != nil {
+	if err := util.SaveIndex(filename, core.IndexVersion, index); err != nil {
 		log.Fatalf("Failed to save puzzles index: %v", err)
 	}
 
 	filename, _ = filepath.Abs(filename)
 	log.Printf("Saved to %q", filename)
+
+	filename = "solutions.index"
+	if err := util.SaveIndex(filename, core.IndexVersion, solutions); err != nil {
+		log.Fatalf("Failed to save solutions index: %v", err)
+	}
+
+	filename, _ = filepath.Abs(filename)
+	log.Printf("Saved to %q", filename)
 }
 
-func CreatePuzzlesIndex(from string) (core.PuzzlesIndex, error) {
+func CreatePuzzlesIndex(from string) (core.PuzzlesIndex, core.SolutionsIndex, error) {
 	index := make(core.PuzzlesIndex, AssumedPuzzleCount)
+	solutions := make(core.SolutionsIndex, AssumedPuzzleCount)
 
 	filename := from
 	file, err := os.Open(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file %q: %w", filename, err)
+		return nil, nil, fmt.Errorf("failed to open file %q: %w", filename, err)
 	}
 	defer file.Close()
 
 	decoder, err := zstd.NewReader(file)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create zst decoder for %q: %w", filename, err)
+		return nil, nil, fmt.Errorf("failed to create zst decoder for %q: %w", filename, err)
 	}
 	defer decoder.Close()
 
@@ -77,19 +86,34 @@ func CreatePuzzlesIndex(from string) (core.PuzzlesIndex, error) {
 		line, err := reader.Read()
 		if err != nil {
 			if !errors.Is(err, io.EOF) {
-				return nil, fmt.Errorf("failed to read file %q line %d: %w", filename, lineNum, err)
+				return nil, nil, fmt.Errorf("failed to read file %q line %d: %w", filename, lineNum, err)
 			}
 			break
 		}
 
 		if lineNum > 1 { // skip the header
 			if len(line) != 10 {
-				return nil, fmt.Errorf("file %q line %d: want 10 fields, have %d", filename, lineNum, len(line))
+				return nil, nil, fmt.Errorf("file %q line %d: want 10 fields, have %d", filename, lineNum, len(line))
 			}
 
 			if len(line[9]) > 0 {
-				if err := index.Insert(line[0], line[1], line[8], line[9]); err != nil {
-					return nil, fmt.Errorf("file %q line %d: %w", filename, lineNum, err)
+				record := core.PuzzleRecord{
+					ID:              line[0],
+					FEN:             line[1],
+					Moves:           line[2],
+					Rating:          line[3],
+					RatingDeviation: line[4],
+					Popularity:      line[5],
+					NbPlays:         line[6],
+					Themes:          line[7],
+					GameURL:         line[8],
+					OpeningTags:     line[9],
+				}
+				if err := index.Insert(record); err != nil {
+					return nil, nil, fmt.Errorf("file %q line %d: %w", filename, lineNum, err)
+				}
+				if err := solutions.Insert(record); err != nil {
+					return nil, nil, fmt.Errorf("file %q line %d: %w", filename, lineNum, err)
 				}
 				indexed++
 			}
@@ -102,7 +126,7 @@ func CreatePuzzlesIndex(from string) (core.PuzzlesIndex, error) {
 	fmt.Println()
 	slog.Debug("created puzzles index", "from", from, "processed", processed, "indexed", indexed)
 
-	return index, nil
+	return index, solutions, nil
 }
 
 func percent(num, of int) float32 {
@@ -39,3 +39,53 @@ func SaveBinary[T any](filename string, t T) error {
 
 	return nil
 }
+
+// LoadIndex reads binary file saved by SaveIndex,
+// refusing files of different format version
+func LoadIndex[T any](filename string, version uint32) (t T, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		err = fmt.Errorf("failed to open index file %q: %w", filename, err)
+		return
+	}
+	defer file.Close()
+
+	decoder := gob.NewDecoder(file)
+
+	var fileVersion uint32
+	if err = decoder.Decode(&fileVersion); err != nil || fileVersion != version {
+		err = fmt.Errorf("unsupported index file %q version: want %d, have %d", filename, version, fileVersion)
+		return
+	}
+
+	if err = decoder.Decode(&t); err != nil {
+		err = fmt.Errorf("failed to read index file: %w", err)
+		return
+	}
+
+	return
+}
+
+// SaveIndex writes binary file prefixed with format version
+func SaveIndex[T any](filename string, version uint32, t T) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create index file %q: %w", filename, err)
+	}
+	defer file.Close()
+
+	encoder := gob.NewEncoder(file)
+	if err = encoder.Encode(version); err != nil {
+		return fmt.Errorf("failed to write index version: %w", err)
+	}
+
+	if err = encoder.Encode(t); err != nil {
+		return fmt.Errorf("failed to write index file: %w", err)
+	}
+
+	if err = file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync index file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,436 @@
+package ui
+
+import (
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/io/event"
+	"gioui.org/io/pointer"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/failosof/cops/core"
+	"github.com/notnil/chess"
+)
+
+var (
+	lightSquareColor = color.NRGBA{R: 0xF0, G: 0xD9, B: 0xB5, A: 0xFF}
+	darkSquareColor  = color.NRGBA{R: 0xB5, G: 0x88, B: 0x63, A: 0xFF}
+)
+
+// EditorMode is what the board editor edits
+type EditorMode int8
+
+const (
+	PatternMode EditorMode = iota
+	SetupMode
+)
+
+func (m EditorMode) String() string {
+	switch m {
+	case PatternMode:
+		return "Board pattern"
+	case SetupMode:
+		return "Position setup"
+	default:
+		panic("unreachable")
+	}
+}
+
+// paletteTools are the pieces to place, no piece stands for
+// the empty square rule of the pattern or erases the setup one
+var paletteTools = []chess.Piece{
+	chess.WhiteKing, chess.WhiteQueen, chess.WhiteRook, chess.WhiteBishop, chess.WhiteKnight, chess.WhitePawn,
+	chess.BlackKing, chess.BlackQueen, chess.BlackRook, chess.BlackBishop, chess.BlackKnight, chess.BlackPawn,
+	chess.NoPiece,
+}
+
+// BoardEditor draws the partial board or sets the position up, pieces are
+// placed by clicking the square with the palette piece selected or by
+// dragging the piece from the palette; in the pattern the placed piece is
+// required on the square, placed again it's forbidden, then cleared; in the
+// setup it's cleared when placed again; pieces dragged off the board are
+// removed and dragged to another square are moved
+type BoardEditor struct {
+	theme   *material.Theme
+	padding unit.Dp
+	mode    *OptionSelector[EditorMode]
+	pattern core.BoardPattern
+	setup   core.Setup
+	tool    chess.Piece
+	flipped bool
+
+	// setup options
+	turn       *OptionSelector[core.Turn]
+	castling   [4]widget.Bool // in the castling rights order, KQkq
+	moveNumber *TextField
+	enPassant  *TextField
+	use        *IconButton
+	status     material.LabelStyle
+
+	// layout of the last frame, to hit test the pointer
+	square int
+	tile   int
+
+	dragging bool
+	dragFrom chess.Square // no square if dragged from the palette
+	dragTool chess.Piece
+	dragAt   f32.Point
+}
+
+// castlingRights are the castling checkboxes labels, as in FEN
+var castlingRights = [4]string{"K", "Q", "k", "q"}
+
+func NewBoardEditor(th *material.Theme) *BoardEditor {
+	status := material.Caption(th, "")
+	status.Color = RedColor
+	return &BoardEditor{
+		theme:      th,
+		padding:    unit.Dp(5),
+		mode:       NewOptionSelector(th, []EditorMode{PatternMode, SetupMode}),
+		tool:       chess.WhiteKnight,
+		turn:       NewOptionSelector(th, []core.Turn{core.WhiteTurn, core.BlackTurn}),
+		moveNumber: NewTextField(th, "Move", SingleLine),
+		enPassant:  NewTextField(th, "En passant", SingleLine),
+		use:        NewIconButton(th, SearchIcon, GreenColor),
+		status:     status,
+	}
+}
+
+func (e *BoardEditor) Mode() EditorMode {
+	return e.mode.Selected()
+}
+
+func (e *BoardEditor) SetMode(mode EditorMode) {
+	e.mode.Set(mode)
+}
+
+func (e *BoardEditor) Pattern() core.BoardPattern {
+	return e.pattern
+}
+
+func (e *BoardEditor) SetPattern(pattern core.BoardPattern) {
+	e.pattern = pattern
+}
+
+// Setup reads the position set up along with its options
+func (e *BoardEditor) Setup() (setup core.Setup) {
+	setup = e.setup
+	setup.Turn = e.turn.Selected().ToChess()
+
+	var castling strings.Builder
+	for i, right := range castlingRights {
+		if e.castling[i].Value {
+			castling.WriteString(right)
+		}
+	}
+	setup.Castling = chess.CastleRights(castling.String())
+	setup.EnPassant = strings.ToLower(strings.TrimSpace(e.enPassant.Text()))
+
+	setup.MoveNumber, _ = strconv.Atoi(e.moveNumber.Text())
+	if len(e.moveNumber.Text()) == 0 {
+		setup.MoveNumber = 1
+	}
+	return
+}
+
+// SetSetup starts the setup from the position, e.g. the board one
+func (e *BoardEditor) SetSetup(setup core.Setup) {
+	e.setup = setup
+	e.turn.Set(core.TurnFromChess(setup.Turn))
+	for i, right := range castlingRights {
+		e.castling[i].Value = strings.Contains(string(setup.Castling), right)
+	}
+	e.moveNumber.SetText(strconv.Itoa(setup.MoveNumber))
+	e.moveNumber.SetError(false)
+	e.enPassant.SetText(setup.EnPassant)
+	e.status.Text = ""
+}
+
+// Position validates the setup, showing why it's invalid
+func (e *BoardEditor) Position() (position *chess.Position, ok bool) {
+	setup := e.Setup()
+	e.moveNumber.SetError(setup.MoveNumber < 1)
+	position, err := setup.Position()
+	if err != nil {
+		e.status.Text = err.Error()
+		return nil, false
+	}
+	e.status.Text = ""
+	return position, true
+}
+
+// ShouldUse reports whether the setup is to be used as the search position
+func (e *BoardEditor) ShouldUse(gtx layout.Context) bool {
+	return e.use.button.Clicked(gtx)
+}
+
+// Clear removes the pattern rules or all the setup pieces
+func (e *BoardEditor) Clear() {
+	if e.Mode() == SetupMode {
+		e.setup.Board = core.Board{}
+		e.enPassant.SetText("")
+		return
+	}
+	e.pattern = nil
+}
+
+func (e *BoardEditor) Flip() {
+	e.flipped = !e.flipped
+}
+
+func (e *BoardEditor) Layout(gtx layout.Context) layout.Dimensions {
+	children := []layout.FlexChild{
+		layout.Rigid(e.mode.Layout),
+		layout.Flexed(1, e.layoutBoard),
+	}
+	if e.Mode() == SetupMode {
+		children = append(children,
+			layout.Rigid(layout.Spacer{Height: e.padding}.Layout),
+			layout.Rigid(e.layoutSetup),
+			layout.Rigid(e.status.Layout),
+		)
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+func (e *BoardEditor) layoutSetup(gtx layout.Context) layout.Dimensions {
+	children := []layout.FlexChild{
+		layout.Rigid(e.turn.Layout),
+	}
+	for i, right := range castlingRights {
+		children = append(children, layout.Rigid(material.CheckBox(e.theme, &e.castling[i], right).Layout))
+	}
+	children = append(children,
+		layout.Rigid(layout.Spacer{Width: e.padding}.Layout),
+		layout.Flexed(1, e.moveNumber.Layout),
+		layout.Rigid(layout.Spacer{Width: e.padding}.Layout),
+		layout.Flexed(1, e.enPassant.Layout),
+		layout.Rigid(layout.Spacer{Width: e.padding}.Layout),
+		layout.Rigid(e.use.Layout),
+	)
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, children...)
+}
+
+func (e *BoardEditor) layoutBoard(gtx layout.Context) layout.Dimensions {
+	e.handlePointer(gtx)
+
+	// the palette row is under the board
+	side := min(gtx.Constraints.Max.X, gtx.Constraints.Max.Y*len(paletteTools)/(len(paletteTools)+1))
+	e.square = side / 8
+	e.tile = side / len(paletteTools)
+	size := image.Pt(e.square*8, e.square*8+e.tile)
+
+	defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+	event.Op(gtx.Ops, e)
+
+	for sq := chess.A1; sq <= chess.H8; sq++ {
+		bounds := e.squareBounds(sq)
+		squareColor := darkSquareColor
+		if (int(sq.File())+int(sq.Rank()))%2 == 1 {
+			squareColor = lightSquareColor
+		}
+		paint.FillShape(gtx.Ops, squareColor, clip.Rect(bounds).Op())
+
+		if rule, ok := e.rule(sq); ok && !(e.dragging && e.dragFrom == sq) {
+			e.layoutPiece(gtx, bounds, rule.Piece, rule.Forbidden)
+		}
+	}
+
+	for i, tool := range paletteTools {
+		bounds := image.Rect(i*e.tile, e.square*8, (i+1)*e.tile, e.square*8+e.tile)
+		if tool == e.tool {
+			paint.FillShape(gtx.Ops, Transparentize(GreenColor, 0.3), clip.Rect(bounds).Op())
+		}
+		e.layoutPiece(gtx, bounds, tool, false)
+	}
+
+	if e.dragging {
+		half := e.square / 2
+		at := e.dragAt.Round()
+		bounds := image.Rect(at.X-half, at.Y-half, at.X+half, at.Y+half)
+		if rule, ok := e.rule(e.dragFrom); ok {
+			e.layoutPiece(gtx, bounds, rule.Piece, rule.Forbidden)
+		} else {
+			e.layoutPiece(gtx, bounds, e.dragTool, false)
+		}
+	}
+
+	return layout.Dimensions{Size: size}
+}
+
+// layoutPiece draws the piece letter on a disc of the piece color,
+// forbidden pieces are red; no piece is drawn as a dot
+func (e *BoardEditor) layoutPiece(gtx layout.Context, bounds image.Rectangle, piece chess.Piece, forbidden bool) {
+	inset := bounds.Dx() / 8
+	disc := image.Rect(bounds.Min.X+inset, bounds.Min.Y+inset, bounds.Max.X-inset, bounds.Max.Y-inset)
+
+	letter, textColor, discColor := "·", BlackColor, color.NRGBA{}
+	switch piece.Color() {
+	case chess.White:
+		letter, textColor, discColor = piece.Type().String(), BlackColor, WhiteColor
+	case chess.Black:
+		letter, textColor, discColor = piece.Type().String(), WhiteColor, BlackColor
+	}
+	if forbidden {
+		discColor = RedColor
+	}
+
+	if discColor.A > 0 {
+		paint.FillShape(gtx.Ops, discColor, clip.Ellipse(disc).Op(gtx.Ops))
+	}
+
+	defer op.Offset(disc.Min).Push(gtx.Ops).Pop()
+	gtx.Constraints = layout.Exact(disc.Size())
+	label := material.Label(e.theme, gtx.Metric.PxToSp(disc.Dy()*2/3), letter)
+	label.Color = textColor
+	label.Alignment = text.Middle
+	layout.Center.Layout(gtx, label.Layout)
+}
+
+func (e *BoardEditor) squareBounds(sq chess.Square) image.Rectangle {
+	file, rank := int(sq.File()), 7-int(sq.Rank())
+	if e.flipped {
+		file, rank = 7-file, 7-rank
+	}
+	return image.Rect(file*e.square, rank*e.square, (file+1)*e.square, (rank+1)*e.square)
+}
+
+// squareAt returns the square under the point, if any
+func (e *BoardEditor) squareAt(p f32.Point) (sq chess.Square, ok bool) {
+	if e.square == 0 || p.X < 0 || p.Y < 0 {
+		return chess.NoSquare, false
+	}
+	file, rank := int(p.X)/e.square, int(p.Y)/e.square
+	if file > 7 || rank > 7 {
+		return chess.NoSquare, false
+	}
+	if e.flipped {
+		file, rank = 7-file, 7-rank
+	}
+	return chess.NewSquare(chess.File(file), chess.Rank(7-rank)), true
+}
+
+// toolAt returns the palette piece under the point, if any
+func (e *BoardEditor) toolAt(p f32.Point) (tool chess.Piece, ok bool) {
+	if e.tile == 0 || p.X < 0 || int(p.Y) < e.square*8 || int(p.Y) >= e.square*8+e.tile {
+		return chess.NoPiece, false
+	}
+	if i := int(p.X) / e.tile; i < len(paletteTools) {
+		return paletteTools[i], true
+	}
+	return chess.NoPiece, false
+}
+
+func (e *BoardEditor) handlePointer(gtx layout.Context) {
+	for {
+		ev, ok := gtx.Event(pointer.Filter{
+			Target: e,
+			Kinds:  pointer.Press | pointer.Drag | pointer.Release | pointer.Cancel,
+		})
+		if !ok {
+			break
+		}
+		pe, ok := ev.(pointer.Event)
+		if !ok {
+			continue
+		}
+
+		switch pe.Kind {
+		case pointer.Press:
+			e.press(pe.Position)
+		case pointer.Drag:
+			e.dragAt = pe.Position
+		case pointer.Release:
+			e.release(pe.Position)
+		case pointer.Cancel:
+			e.dragging = false
+		}
+	}
+}
+
+func (e *BoardEditor) press(p f32.Point) {
+	if tool, ok := e.toolAt(p); ok {
+		e.tool = tool
+		e.dragging, e.dragFrom, e.dragTool, e.dragAt = true, chess.NoSquare, tool, p
+		return
+	}
+	if sq, ok := e.squareAt(p); ok {
+		e.dragging, e.dragFrom, e.dragTool, e.dragAt = true, sq, e.tool, p
+	}
+}
+
+func (e *BoardEditor) release(p f32.Point) {
+	if !e.dragging {
+		return
+	}
+	e.dragging = false
+
+	to, onBoard := e.squareAt(p)
+	rule, ruled := e.rule(e.dragFrom)
+	switch {
+	case e.dragFrom == chess.NoSquare: // from the palette
+		if onBoard {
+			e.setRule(core.SquareRule{Square: to, Piece: e.dragTool})
+		}
+	case !onBoard:
+		e.clearRule(e.dragFrom)
+	case to == e.dragFrom: // clicked
+		e.toggle(to)
+	case ruled:
+		rule.Square = to
+		e.clearRule(e.dragFrom)
+		e.setRule(rule)
+	}
+}
+
+// toggle requires the selected piece on the square, forbids it if
+// it's required and clears if forbidden; in the setup the piece
+// is placed or cleared if it's already there
+func (e *BoardEditor) toggle(sq chess.Square) {
+	rule, ok := e.rule(sq)
+	switch {
+	case !ok || rule.Piece != e.tool:
+		e.setRule(core.SquareRule{Square: sq, Piece: e.tool})
+	case !rule.Forbidden && e.Mode() == PatternMode:
+		e.setRule(core.SquareRule{Square: sq, Piece: e.tool, Forbidden: true})
+	default:
+		e.clearRule(sq)
+	}
+}
+
+// rule returns the square rule of the pattern,
+// in the setup the rule requires the piece there
+func (e *BoardEditor) rule(sq chess.Square) (rule core.SquareRule, ok bool) {
+	if e.Mode() == PatternMode {
+		return e.pattern.Rule(sq)
+	}
+	if sq == chess.NoSquare || e.setup.Board[sq] == chess.NoPiece {
+		return rule, false
+	}
+	return core.SquareRule{Square: sq, Piece: e.setup.Board[sq]}, true
+}
+
+func (e *BoardEditor) setRule(rule core.SquareRule) {
+	if e.Mode() == PatternMode {
+		e.pattern = e.pattern.Set(rule)
+		return
+	}
+	e.setup.Board[rule.Square] = rule.Piece // the empty square erases
+}
+
+func (e *BoardEditor) clearRule(sq chess.Square) {
+	if e.Mode() == PatternMode {
+		e.pattern = e.pattern.Clear(sq)
+		return
+	}
+	e.setup.Board[sq] = chess.NoPiece
+}
@@ -0,0 +1,175 @@
+package ui
+
+import (
+	"fmt"
+
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/failosof/cops/core"
+)
+
+// OpeningExplorer shows the openings tree, clicked family or
+// variation is expanded or collapsed; while the name is searched
+// the tree is replaced by the found openings
+type OpeningExplorer struct {
+	theme       *material.Theme
+	padding     unit.Dp
+	search      *TextField
+	list        *widget.List
+	root        *core.OpeningNode
+	expanded    map[*core.OpeningNode]bool
+	suggestions []*core.OpeningNode
+	rows        []*core.OpeningNode
+	selects     []widget.Clickable
+	count       func(*core.OpeningNode) int
+}
+
+func NewOpeningExplorer(th *material.Theme) *OpeningExplorer {
+	return &OpeningExplorer{
+		theme:    th,
+		padding:  unit.Dp(5),
+		search:   NewTextField(th, "Opening name, e.g. Najdorf", SingleLine),
+		list:     &widget.List{List: layout.List{Axis: layout.Vertical}},
+		expanded: make(map[*core.OpeningNode]bool),
+	}
+}
+
+// Searched returns the entered opening name if it's changed
+func (e *OpeningExplorer) Searched(gtx layout.Context) (query string, ok bool) {
+	if e.search.Changed(gtx) {
+		return e.search.Text(), true
+	}
+	return
+}
+
+// SetSuggestions shows the found openings instead of the tree,
+// the tree is shown back when there are no suggestions
+func (e *OpeningExplorer) SetSuggestions(nodes []*core.OpeningNode) {
+	e.suggestions = nodes
+	e.search.SetError(len(nodes) == 0 && len(e.search.Text()) > 0)
+	e.list.Position = layout.Position{}
+	e.refresh()
+}
+
+// SetTree shows the tree, count is called for the
+// shown nodes only, as it can be slow for big trees
+func (e *OpeningExplorer) SetTree(root *core.OpeningNode, count func(*core.OpeningNode) int) {
+	e.root = root
+	e.count = count
+	e.refresh()
+}
+
+func (e *OpeningExplorer) refresh() {
+	e.rows = e.rows[:0]
+	if len(e.suggestions) > 0 {
+		e.rows = append(e.rows, e.suggestions...)
+		e.resize()
+		return
+	}
+
+	var walk func(node *core.OpeningNode)
+	walk = func(node *core.OpeningNode) {
+		for _, child := range node.Children {
+			e.rows = append(e.rows, child)
+			if e.expanded[child] {
+				walk(child)
+			}
+		}
+	}
+	if e.root != nil {
+		walk(e.root)
+	}
+	e.resize()
+}
+
+func (e *OpeningExplorer) resize() {
+	if len(e.selects) < len(e.rows) {
+		e.selects = make([]widget.Clickable, len(e.rows))
+	}
+}
+
+// Clicked returns the clicked node, expanding or collapsing it
+func (e *OpeningExplorer) Clicked(gtx layout.Context) (node *core.OpeningNode, ok bool) {
+	for i, row := range e.rows {
+		if e.selects[i].Clicked(gtx) {
+			node, ok = row, true
+		}
+	}
+	if ok && len(e.suggestions) == 0 && len(node.Children) > 0 {
+		e.expanded[node] = !e.expanded[node]
+		e.refresh()
+	}
+	return
+}
+
+func (e *OpeningExplorer) Layout(gtx layout.Context) layout.Dimensions {
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(e.search.Layout),
+		layout.Rigid(layout.Spacer{Height: e.padding}.Layout),
+		layout.Flexed(1, e.layoutList),
+	)
+}
+
+func (e *OpeningExplorer) layoutList(gtx layout.Context) layout.Dimensions {
+	return widget.Border{
+		Color:        BlackColor,
+		CornerRadius: unit.Dp(1),
+		Width:        unit.Dp(1),
+	}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		if len(e.rows) == 0 {
+			return Pad(e.padding, material.Body1(e.theme, "No openings").Layout)(gtx)
+		}
+		return material.List(e.theme, e.list).Layout(gtx, len(e.rows), e.layoutRow)
+	})
+}
+
+func (e *OpeningExplorer) layoutRow(gtx layout.Context, i int) layout.Dimensions {
+	node := e.rows[i]
+	if len(e.suggestions) > 0 {
+		return e.layoutSuggestion(gtx, i)
+	}
+
+	marker := "  "
+	if len(node.Children) > 0 {
+		marker = "▸ "
+		if e.expanded[node] {
+			marker = "▾ "
+		}
+	}
+
+	title := material.Body1(e.theme, marker+node.Name)
+	if node.Opening == nil {
+		title.Color = GrayColor // nothing to play
+	}
+
+	info := fmt.Sprintf("%d puzzles", e.count(node))
+	if node.Opening != nil {
+		info = node.Opening.Name.ECO + " · " + info
+	}
+	caption := material.Caption(e.theme, info)
+	caption.Color = GrayColor
+
+	return e.layoutClickable(gtx, i, unit.Dp(15*node.Depth()), title, caption)
+}
+
+// layoutSuggestion shows the full opening name, as it's out of the tree
+func (e *OpeningExplorer) layoutSuggestion(gtx layout.Context, i int) layout.Dimensions {
+	node := e.rows[i]
+	title := material.Body1(e.theme, node.Opening.Name.String())
+	caption := material.Caption(e.theme, fmt.Sprintf("%s · %d puzzles", node.Opening.Name.ECO, e.count(node)))
+	caption.Color = GrayColor
+	return e.layoutClickable(gtx, i, 0, title, caption)
+}
+
+func (e *OpeningExplorer) layoutClickable(gtx layout.Context, i int, indent unit.Dp, title, caption material.LabelStyle) layout.Dimensions {
+	return material.Clickable(gtx, &e.selects[i], func(gtx layout.Context) layout.Dimensions {
+		return layout.Inset{Left: indent + e.padding, Right: e.padding, Top: e.padding, Bottom: e.padding}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Flexed(1, title.Layout),
+				layout.Rigid(caption.Layout),
+			)
+		})
+	})
+}
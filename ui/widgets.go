@@ -3,14 +3,20 @@ package ui
 import (
 	"fmt"
 	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
 	"gioui.org/unit"
 	"gioui.org/widget"
 	"gioui.org/widget/material"
 	"github.com/failosof/cops/core"
 	"golang.org/x/exp/shiny/materialdesign/icons"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
 	"image/color"
 	"math"
 	"strconv"
+	"strings"
 )
 
 type TextFieldOption int8
@@ -25,6 +31,8 @@ type TextField struct {
 	border  *widget.Border
 	editor  *widget.Editor
 	style   material.EditorStyle
+	err     material.LabelStyle
+	text    string // set by the program, not entered
 }
 
 func NewTextField(th *material.Theme, hint string, options TextFieldOption) *TextField {
@@ -32,6 +40,8 @@ func NewTextField(th *material.Theme, hint string, options TextFieldOption) *Tex
 		ReadOnly:   options&ReadOnly != 0,
 		SingleLine: options&SingleLine != 0,
 	}
+	err := material.Caption(th, "")
+	err.Color = RedColor
 	return &TextField{
 		padding: unit.Dp(7),
 		border: &widget.Border{
@@ -41,40 +51,105 @@ func NewTextField(th *material.Theme, hint string, options TextFieldOption) *Tex
 		},
 		editor: &editor,
 		style:  material.Editor(th, &editor, hint),
+		err:    err,
 	}
 }
 
 func (w *TextField) SetText(text string) {
+	w.text = text
 	w.editor.SetText(text)
 }
 
+// Changed reports whether the text is edited by user since the last call
+func (w *TextField) Changed(gtx layout.Context) (changed bool) {
+	for {
+		event, ok := w.editor.Update(gtx)
+		if !ok {
+			break
+		}
+		if _, ok := event.(widget.ChangeEvent); ok {
+			changed = true
+		}
+	}
+	return changed && w.editor.Text() != w.text
+}
+
+func (w *TextField) Text() string {
+	return strings.TrimSpace(w.editor.Text())
+}
+
+// SetError highlights the field border
+func (w *TextField) SetError(failed bool) {
+	if failed {
+		w.border.Color = RedColor
+	} else {
+		w.border.Color = BlackColor
+	}
+}
+
+// ShowError highlights the field and shows the error message
+// under it, the message is hidden when there is no error
+func (w *TextField) ShowError(err error) {
+	w.SetError(err != nil)
+	if err != nil {
+		w.err.Text = err.Error()
+	} else {
+		w.err.Text = ""
+	}
+}
+
 func (w *TextField) Layout(gtx layout.Context) layout.Dimensions {
-	return w.border.Layout(gtx, Pad(w.padding, w.style.Layout))
+	if len(w.err.Text) == 0 {
+		return w.border.Layout(gtx, Pad(w.padding, w.style.Layout))
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return w.border.Layout(gtx, Pad(w.padding, w.style.Layout))
+		}),
+		layout.Rigid(w.err.Layout),
+	)
 }
 
+// OpeningName shows the opening of the board position, noting
+// if the position is reached by another move order than the book one
 type OpeningName struct {
 	family    *TextField
 	variation *TextField
+	note      material.LabelStyle
 }
 
 func NewOpeningName(th *material.Theme) *OpeningName {
+	note := material.Caption(th, "")
+	note.Color = GrayColor
 	return &OpeningName{
 		family:    NewTextField(th, "Family", ReadOnly|SingleLine),
 		variation: NewTextField(th, "Variation", ReadOnly|SingleLine),
+		note:      note,
 	}
 }
 
 func (w *OpeningName) Layout(gtx layout.Context) layout.Dimensions {
-	return layout.Flex{Axis: layout.Vertical, Spacing: layout.SpaceEnd}.Layout(gtx,
+	children := []layout.FlexChild{
 		layout.Rigid(w.family.Layout),
 		layout.Rigid(layout.Spacer{Height: unit.Dp(3)}.Layout),
 		layout.Rigid(w.variation.Layout),
-	)
+	}
+	if len(w.note.Text) > 0 {
+		children = append(children, layout.Rigid(w.note.Layout))
+	}
+	return layout.Flex{Axis: layout.Vertical, Spacing: layout.SpaceEnd}.Layout(gtx, children...)
 }
 
+// Set shows the name, clearing the transposition note
 func (w *OpeningName) Set(name core.OpeningName) {
-	w.family.SetText(name.Family())
-	w.variation.SetText(name.Variation())
+	w.family.SetText(strings.TrimSpace(name.ECO + " " + name.Family()))
+	w.variation.SetText(strings.Join(name.Variations(), ", "))
+	w.note.Text = ""
+}
+
+// SetTransposition notes the book line of the opening
+func (w *OpeningName) SetTransposition(book string) {
+	w.note.Text = "Transposed, book line: " + book
 }
 
 type Icon []byte
@@ -85,6 +160,19 @@ var (
 	BackwardIcon Icon = icons.NavigationArrowBack
 	ForwardIcon  Icon = icons.NavigationArrowForward
 	SearchIcon   Icon = icons.ActionSearch
+	StopIcon     Icon = icons.AVStop
+	FirstIcon    Icon = icons.NavigationFirstPage
+	PreviousIcon Icon = icons.NavigationChevronLeft
+	NextIcon     Icon = icons.NavigationChevronRight
+	LastIcon     Icon = icons.NavigationLastPage
+	BrowserIcon  Icon = icons.ActionOpenInBrowser
+	SolveIcon    Icon = icons.ActionExtension
+	DueIcon      Icon = icons.ActionSchedule
+	SaveIcon     Icon = icons.ContentSave
+	LoadIcon     Icon = icons.FileFolderOpen
+	DiffIcon     Icon = icons.ActionCompareArrows
+	ExportIcon   Icon = icons.FileFileDownload
+	EditIcon     Icon = icons.ImageEdit
 )
 
 type IconButton struct {
@@ -128,6 +216,7 @@ type BoardControls struct {
 	backward *IconButton
 	forward  *IconButton
 	flip     *IconButton
+	edit     *IconButton
 }
 
 func NewBoardControls(th *material.Theme) *BoardControls {
@@ -137,6 +226,7 @@ func NewBoardControls(th *material.Theme) *BoardControls {
 		backward: NewIconButton(th, BackwardIcon, GrayColor),
 		forward:  NewIconButton(th, ForwardIcon, GrayColor),
 		flip:     NewIconButton(th, FlipIcon, BlueColor),
+		edit:     NewIconButton(th, EditIcon, YellowColor),
 	}
 }
 
@@ -149,6 +239,8 @@ func (c *BoardControls) Layout(gtx layout.Context) layout.Dimensions {
 		//layout.Flexed(1, c.forward.Layout),
 		layout.Rigid(layout.Spacer{Width: c.padding}.Layout),
 		layout.Flexed(1, c.flip.Layout),
+		layout.Rigid(layout.Spacer{Width: c.padding}.Layout),
+		layout.Flexed(1, c.edit.Layout),
 	)
 }
 
@@ -168,6 +260,319 @@ func (c *BoardControls) ShouldFlip(gtx layout.Context) bool {
 	return c.flip.button.Clicked(gtx)
 }
 
+func (c *BoardControls) ShouldEdit(gtx layout.Context) bool {
+	return c.edit.button.Clicked(gtx)
+}
+
+type ResultRow struct {
+	Puzzle  core.PuzzleData
+	Opening core.OpeningName
+}
+
+type ResultList struct {
+	theme    *material.Theme
+	padding  unit.Dp
+	list     *widget.List
+	rows     []ResultRow
+	selects  []widget.Clickable
+	opens    []*IconButton
+	solves   []*IconButton
+	selected core.PuzzleID
+}
+
+func NewResultList(th *material.Theme, size int) *ResultList {
+	opens := make([]*IconButton, size)
+	solves := make([]*IconButton, size)
+	for i := range opens {
+		opens[i] = NewIconButton(th, BrowserIcon, BlueColor)
+		solves[i] = NewIconButton(th, SolveIcon, GreenColor)
+	}
+	return &ResultList{
+		theme:   th,
+		padding: unit.Dp(5),
+		list:    &widget.List{List: layout.List{Axis: layout.Vertical}},
+		selects: make([]widget.Clickable, size),
+		opens:   opens,
+		solves:  solves,
+	}
+}
+
+// SetRows replaces the shown rows, there can't be
+// more rows than the list size it was created with
+func (l *ResultList) SetRows(rows []ResultRow) {
+	rows = rows[:min(len(rows), len(l.selects))]
+	if len(rows) == 0 || len(l.rows) == 0 || rows[0].Puzzle.ID != l.rows[0].Puzzle.ID {
+		l.list.Position = layout.Position{} // scroll up on another page
+	}
+	l.rows = rows
+}
+
+// Selected returns the puzzle which row is clicked
+func (l *ResultList) Selected(gtx layout.Context) (puzzle core.PuzzleData, ok bool) {
+	for i, row := range l.rows {
+		if l.selects[i].Clicked(gtx) {
+			l.selected = row.Puzzle.ID
+			puzzle, ok = row.Puzzle, true
+		}
+	}
+	return
+}
+
+// Opened returns the puzzle which open button is clicked
+func (l *ResultList) Opened(gtx layout.Context) (puzzle core.PuzzleData, ok bool) {
+	for i, row := range l.rows {
+		if l.opens[i].button.Clicked(gtx) {
+			puzzle, ok = row.Puzzle, true
+		}
+	}
+	return
+}
+
+// Solving returns the puzzle which solve button is clicked
+func (l *ResultList) Solving(gtx layout.Context) (puzzle core.PuzzleData, ok bool) {
+	for i, row := range l.rows {
+		if l.solves[i].button.Clicked(gtx) {
+			l.selected = row.Puzzle.ID
+			puzzle, ok = row.Puzzle, true
+		}
+	}
+	return
+}
+
+func (l *ResultList) Layout(gtx layout.Context) layout.Dimensions {
+	return widget.Border{
+		Color:        BlackColor,
+		CornerRadius: unit.Dp(1),
+		Width:        unit.Dp(1),
+	}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		if len(l.rows) == 0 {
+			return Pad(l.padding, material.Body1(l.theme, "No puzzles").Layout)(gtx)
+		}
+		return material.List(l.theme, l.list).Layout(gtx, len(l.rows), l.layoutRow)
+	})
+}
+
+func (l *ResultList) layoutRow(gtx layout.Context, i int) layout.Dimensions {
+	row := l.rows[i]
+	return material.Clickable(gtx, &l.selects[i], func(gtx layout.Context) layout.Dimensions {
+		return layout.Background{}.Layout(gtx,
+			func(gtx layout.Context) layout.Dimensions {
+				if row.Puzzle.ID == l.selected {
+					defer clip.Rect{Max: gtx.Constraints.Min}.Push(gtx.Ops).Pop()
+					paint.Fill(gtx.Ops, Transparentize(GreenColor, 0.3))
+				}
+				return layout.Dimensions{Size: gtx.Constraints.Min}
+			},
+			Pad(l.padding, func(gtx layout.Context) layout.Dimensions {
+				title := material.Body1(l.theme, fmt.Sprintf("%s · move %d · %s · %d", row.Puzzle.ID, row.Puzzle.Move, row.Puzzle.Turn.Name(), row.Puzzle.Rating))
+				opening := material.Caption(l.theme, row.Opening.Title())
+				opening.Color = GrayColor
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+						return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+							layout.Rigid(title.Layout),
+							layout.Rigid(opening.Layout),
+						)
+					}),
+					layout.Rigid(l.solves[i].Layout),
+					layout.Rigid(layout.Spacer{Width: l.padding}.Layout),
+					layout.Rigid(l.opens[i].Layout),
+				)
+			}),
+		)
+	})
+}
+
+type Pagination struct {
+	padding  unit.Dp
+	first    *IconButton
+	previous *IconButton
+	next     *IconButton
+	last     *IconButton
+	status   material.LabelStyle
+	printer  *message.Printer
+}
+
+func NewPagination(th *material.Theme) *Pagination {
+	status := material.Body1(th, "")
+	status.Alignment = text.Middle
+	return &Pagination{
+		padding:  unit.Dp(5),
+		first:    NewIconButton(th, FirstIcon, GrayColor),
+		previous: NewIconButton(th, PreviousIcon, GrayColor),
+		next:     NewIconButton(th, NextIcon, GrayColor),
+		last:     NewIconButton(th, LastIcon, GrayColor),
+		status:   status,
+		printer:  message.NewPrinter(language.English),
+	}
+}
+
+func (p *Pagination) Layout(gtx layout.Context) layout.Dimensions {
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+		layout.Rigid(p.first.Layout),
+		layout.Rigid(layout.Spacer{Width: p.padding}.Layout),
+		layout.Rigid(p.previous.Layout),
+		layout.Flexed(1, Pad(p.padding, p.status.Layout)),
+		layout.Rigid(p.next.Layout),
+		layout.Rigid(layout.Spacer{Width: p.padding}.Layout),
+		layout.Rigid(p.last.Layout),
+	)
+}
+
+// SetStatus shows the range of puzzles on the page, from is inclusive
+func (p *Pagination) SetStatus(from, to, total int) {
+	if total == 0 {
+		p.status.Text = "No puzzles"
+	} else {
+		p.status.Text = p.printer.Sprintf("Showing %d–%d of %d", from+1, to, total)
+	}
+}
+
+func (p *Pagination) ShouldGoFirst(gtx layout.Context) bool {
+	return p.first.button.Clicked(gtx)
+}
+
+func (p *Pagination) ShouldGoPrevious(gtx layout.Context) bool {
+	return p.previous.button.Clicked(gtx)
+}
+
+func (p *Pagination) ShouldGoNext(gtx layout.Context) bool {
+	return p.next.button.Clicked(gtx)
+}
+
+func (p *Pagination) ShouldGoLast(gtx layout.Context) bool {
+	return p.last.button.Clicked(gtx)
+}
+
+// Collections lets to save the search results under
+// a name, to load them back and to compare with a re-run
+type Collections struct {
+	theme   *material.Theme
+	padding unit.Dp
+	name    *TextField
+	save    *IconButton
+	load    *IconButton
+	diff    *IconButton
+	list    *widget.List
+	names   []string
+	selects []widget.Clickable
+	status  material.LabelStyle
+}
+
+func NewCollections(th *material.Theme) *Collections {
+	status := material.Caption(th, "")
+	status.Color = GrayColor
+	return &Collections{
+		theme:   th,
+		padding: unit.Dp(5),
+		name:    NewTextField(th, "Collection name", SingleLine),
+		save:    NewIconButton(th, SaveIcon, GreenColor),
+		load:    NewIconButton(th, LoadIcon, BlueColor),
+		diff:    NewIconButton(th, DiffIcon, YellowColor),
+		list:    &widget.List{List: layout.List{Axis: layout.Horizontal}},
+		status:  status,
+	}
+}
+
+func (c *Collections) Layout(gtx layout.Context) layout.Dimensions {
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Flexed(1, c.name.Layout),
+				layout.Rigid(layout.Spacer{Width: c.padding}.Layout),
+				layout.Rigid(c.save.Layout),
+				layout.Rigid(layout.Spacer{Width: c.padding}.Layout),
+				layout.Rigid(c.load.Layout),
+				layout.Rigid(layout.Spacer{Width: c.padding}.Layout),
+				layout.Rigid(c.diff.Layout),
+			)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return material.List(c.theme, c.list).Layout(gtx, len(c.names), func(gtx layout.Context, i int) layout.Dimensions {
+				return material.Clickable(gtx, &c.selects[i], Pad(c.padding, material.Body2(c.theme, c.names[i]).Layout))
+			})
+		}),
+		layout.Rigid(c.status.Layout),
+	)
+}
+
+func (c *Collections) SetNames(names []string) {
+	c.names = names
+	c.selects = make([]widget.Clickable, len(names))
+}
+
+// Name returns the entered collection name, clicked
+// name of the saved collection is entered first
+func (c *Collections) Name(gtx layout.Context) string {
+	for i, name := range c.names {
+		if c.selects[i].Clicked(gtx) {
+			c.name.SetText(name)
+		}
+	}
+	return c.name.Text()
+}
+
+func (c *Collections) SetStatus(status string, failed bool) {
+	c.status.Text = status
+	c.name.SetError(failed)
+}
+
+func (c *Collections) ShouldSave(gtx layout.Context) bool {
+	return c.save.button.Clicked(gtx)
+}
+
+func (c *Collections) ShouldLoad(gtx layout.Context) bool {
+	return c.load.button.Clicked(gtx)
+}
+
+func (c *Collections) ShouldDiff(gtx layout.Context) bool {
+	return c.diff.button.Clicked(gtx)
+}
+
+// Export lets to write the search results to a file
+type Export struct {
+	padding unit.Dp
+	format  *OptionSelector[core.ExportFormat]
+	export  *IconButton
+	status  material.LabelStyle
+}
+
+func NewExport(th *material.Theme) *Export {
+	status := material.Caption(th, "")
+	status.Color = GrayColor
+	return &Export{
+		padding: unit.Dp(5),
+		format:  NewOptionSelector(th, []core.ExportFormat{core.URLsExport, core.CSVExport, core.JSONExport, core.PGNExport, core.StudyExport}),
+		export:  NewIconButton(th, ExportIcon, BlueColor),
+		status:  status,
+	}
+}
+
+func (e *Export) Layout(gtx layout.Context) layout.Dimensions {
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Flexed(1, e.format.Layout),
+				layout.Rigid(layout.Spacer{Width: e.padding}.Layout),
+				layout.Rigid(e.export.Layout),
+			)
+		}),
+		layout.Rigid(e.status.Layout),
+	)
+}
+
+func (e *Export) Format() core.ExportFormat {
+	return e.format.Selected()
+}
+
+func (e *Export) SetStatus(status string) {
+	e.status.Text = status
+}
+
+func (e *Export) ShouldExport(gtx layout.Context) bool {
+	return e.export.button.Clicked(gtx)
+}
+
 type RangeSlider struct {
 	min, max uint8
 	padding  unit.Dp
@@ -209,7 +614,7 @@ func (s *RangeSlider) Selected() (res uint8) {
 
 func (s *RangeSlider) Set(value uint8) {
 	if s.min <= value && value <= s.max {
-		percent := float32(value) / float32(s.max-s.min)
+		percent := float32(value-s.min+1) / float32(s.max-s.min+1)
 		s.slider.Float.Value = percent
 	} else {
 		s.slider.Float.Value = 0
@@ -252,3 +657,11 @@ func (w *OptionSelector[Option]) Selected() (o Option) {
 	}
 	return
 }
+
+func (w *OptionSelector[Option]) Set(option Option) {
+	for i, o := range w.options {
+		if o.String() == option.String() {
+			w.group.Value = strconv.Itoa(i)
+		}
+	}
+}
@@ -2,8 +2,12 @@ package ui
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"math"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -22,7 +26,30 @@ import (
 	"github.com/notnil/chess"
 )
 
-const PageSize = 30 // puzzles on one page
+type Pane int8
+
+const (
+	ResultsPane Pane = iota
+	OpeningsPane
+)
+
+func (p Pane) String() string {
+	switch p {
+	case ResultsPane:
+		return "Results"
+	case OpeningsPane:
+		return "Openings"
+	default:
+		panic("unreachable")
+	}
+}
+
+const (
+	PageSize               = 30 // puzzles on one page
+	OpeningSuggestions     = 50
+	ResultsRefreshInterval = 200 * time.Millisecond
+	TrainingFile           = "training.json"
+)
 
 type Window struct {
 	window  *app.Window
@@ -31,21 +58,48 @@ type Window struct {
 
 	// left pane
 	opening       *OpeningName
+	solveStatus   material.LabelStyle
 	board         *chessboard.Widget
+	editor        *BoardEditor
+	editing       bool // the board editor is shown instead of the board
 	fen           *TextField
 	pgn           *TextField
 	boardControls *BoardControls
 
 	// right pane
+	pane           *OptionSelector[Pane]
+	explorer       *OpeningExplorer
 	movesCount     *RangeSlider
 	turn           *OptionSelector[core.Turn]
 	searchStrategy *OptionSelector[core.SearchType]
-	puzzles        *TextField
+	tolerance      *TextField
+	pattern        *TextField
+	sameMaterial   widget.Bool
+	minRating      *TextField
+	maxRating      *TextField
+	minPopularity  *TextField
+	themes         *TextField
+	excludedThemes *TextField
+	sortOrder      *OptionSelector[core.SortOrder]
+	collections    *Collections
+	export         *Export
+	puzzles        *ResultList
+	pagination     *Pagination
 
 	search *IconButton
+	cancel *IconButton
+	due    *IconButton
 
 	// state
-	game *chess.Game
+	game   *chess.Game
+	synced boardState // shown in the fields
+
+	solution      *core.PuzzleSolution // nil if not solving
+	solver        chess.Color
+	solvePuzzle   core.PuzzleID
+	solveStart    time.Time
+	solveRecorded bool
+	training      core.TrainingQueue
 
 	resourcesLoaded  atomic.Bool
 	loadingStatus    string
@@ -53,9 +107,13 @@ type Window struct {
 	chessBoardConfig *chessboard.Config
 
 	searching     atomic.Bool
+	searchCancel  context.CancelFunc
+	lastQuery     core.Query // results are found by, if Game is set
 	resultsLoaded atomic.Bool
 	resultsMu     sync.RWMutex
 	results       []core.PuzzleData
+	resultsSort   core.SortOrder
+	page          int // kept as is when results shrink
 }
 
 func NewWindow() (*Window, error) {
@@ -73,15 +131,32 @@ func (w *Window) Show(ctx context.Context) {
 
 	w.loadingStatus = "Loading..."
 	w.opening = NewOpeningName(w.theme)
-	w.fen = NewTextField(w.theme, "FEN", ReadOnly|SingleLine)
-	w.pgn = NewTextField(w.theme, "PGN", ReadOnly)
+	w.solveStatus = material.Body1(w.theme, "")
+	w.fen = NewTextField(w.theme, "FEN", SingleLine)
+	w.pgn = NewTextField(w.theme, "PGN", 0)
+	w.editor = NewBoardEditor(w.theme)
 	w.boardControls = NewBoardControls(w.theme)
 
+	w.pane = NewOptionSelector(w.theme, []Pane{ResultsPane, OpeningsPane})
+	w.explorer = NewOpeningExplorer(w.theme)
 	w.movesCount = NewRangeSlider(w.theme, "Moves", 1, 40)
 	w.turn = NewOptionSelector(w.theme, []core.Turn{core.WhiteTurn, core.BlackTurn, core.EitherTurn})
-	w.searchStrategy = NewOptionSelector(w.theme, []core.SearchType{core.MoveSequenceSearch, core.PositionSearch})
-	w.puzzles = NewTextField(w.theme, "Lichess puzzle links", ReadOnly)
+	w.searchStrategy = NewOptionSelector(w.theme, []core.SearchType{core.MoveSequenceSearch, core.PositionSearch, core.TranspositionSearch, core.PatternSearch, core.StructureSearch, core.BoardSearch})
+	w.tolerance = NewTextField(w.theme, "Plies the moves may be shifted by", SingleLine)
+	w.pattern = NewTextField(w.theme, "Move pattern, e.g. 1. e4 c5 2. Nf3 * 3. d4", SingleLine)
+	w.minRating = NewTextField(w.theme, "Min rating", SingleLine)
+	w.maxRating = NewTextField(w.theme, "Max rating", SingleLine)
+	w.minPopularity = NewTextField(w.theme, "Min popularity", SingleLine)
+	w.themes = NewTextField(w.theme, "Themes, e.g. fork mateIn2", SingleLine)
+	w.excludedThemes = NewTextField(w.theme, "Excluded themes", SingleLine)
+	w.sortOrder = NewOptionSelector(w.theme, []core.SortOrder{core.NoSort, core.SortByRating, core.SortByPopularity, core.SortByMove})
+	w.collections = NewCollections(w.theme)
+	w.export = NewExport(w.theme)
+	w.puzzles = NewResultList(w.theme, PageSize)
+	w.pagination = NewPagination(w.theme)
 	w.search = NewIconButton(w.theme, SearchIcon, GreenColor)
+	w.cancel = NewIconButton(w.theme, StopIcon, RedColor)
+	w.due = NewIconButton(w.theme, DueIcon, BlueColor)
 
 	go func() {
 		if err := w.update(ctx); err != nil {
@@ -122,6 +197,23 @@ func (w *Window) Show(ctx context.Context) {
 
 		w.board = chessboard.NewWidget(w.theme, w.chessBoardConfig)
 
+		collections, err := core.ListCollections()
+		if err != nil {
+			slog.Error("failed to list collections", "err", err)
+		}
+		w.collections.SetNames(collections)
+
+		// training queue is optional, start with an empty one on error
+		w.training, err = resources.LoadConfig[core.TrainingQueue](TrainingFile)
+		if err != nil {
+			slog.Error("failed to load training queue", "err", err)
+		}
+
+		// warm up puzzle openings lookup for the results
+		go w.index.PuzzleOpening(core.PuzzleID{})
+
+		w.explorer.SetTree(w.index.OpeningTree(), w.index.OpeningPuzzles)
+
 		w.resourcesLoaded.Store(true)
 		w.window.Invalidate()
 	}()
@@ -147,11 +239,23 @@ func (w *Window) update(ctx context.Context) error {
 			if w.resourcesLoaded.Load() {
 				if !w.searching.Load() {
 					w.handleControls(gtx)
+					w.handleSolve(gtx)
+					w.handleSolving(gtx)
+					w.handleImport(gtx)
 					w.handleBoard(gtx)
 					w.handleSearch(gtx)
+					w.handleSetup(gtx)
+					w.handleDue(gtx)
+					w.handleCollections(gtx)
+					w.handleExport(gtx)
+					w.handleSort(gtx)
+					w.handlePreview(gtx)
+					w.handleExplorer(gtx)
 				} else {
-					gtx = gtx.Disabled()
+					w.handleCancel(gtx)
 				}
+				w.handlePagination(gtx)
+				w.handleOpen(gtx)
 				w.layoutWidgets(gtx)
 			} else {
 				w.layoutLoading(gtx)
@@ -166,75 +270,648 @@ func (w *Window) update(ctx context.Context) error {
 func (w *Window) handleControls(gtx layout.Context) {
 	switch {
 	case w.boardControls.ShouldReset(gtx):
+		if w.editing {
+			w.editor.Clear()
+			break
+		}
 		w.board.Reset()
+		w.solution = nil
 	case w.boardControls.ShouldMoveBackward(gtx):
 		w.board.MoveBackward()
 	//case w.boardControls.ShouldMoveForward(gtx):
 	//	w.board.MoveForward()
 	case w.boardControls.ShouldFlip(gtx):
 		w.board.Flip()
+		w.editor.Flip()
+	case w.boardControls.ShouldEdit(gtx):
+		w.editing = !w.editing
+		if w.editing { // the setup starts from the board position
+			w.editor.SetSetup(core.SetupFromChess(w.board.Game().Position()))
+		}
 	default:
 		return // do not refresh the screen
 	}
 	w.window.Invalidate()
 }
 
+type boardState struct {
+	game     *chess.Game
+	plies    int
+	position string
+}
+
 func (w *Window) handleBoard(gtx layout.Context) {
 	game := w.board.Game()
+	if state := newBoardState(game); state != w.synced {
+		w.syncBoard(state, nil)
+	}
+}
 
-	openingName, _ := w.index.SearchOpening(game)
-	w.opening.Set(openingName)
+// handleImport loads the edited position or moves to the board
+func (w *Window) handleImport(gtx layout.Context) {
+	switch {
+	case w.fen.Changed(gtx):
+		opt, err := chess.FEN(w.fen.Text())
+		w.fen.ShowError(err)
+		if err != nil {
+			return
+		}
+		w.loadGame(chess.NewGame(opt), w.fen)
+	case w.pgn.Changed(gtx):
+		game, err := core.ParsePGN(w.pgn.Text())
+		w.pgn.ShowError(err)
+		if err != nil {
+			return
+		}
+		w.loadGame(game, w.pgn)
+	default:
+		return // do not refresh the screen
+	}
+	w.window.Invalidate()
+}
 
-	w.fen.SetText(game.Position().String())
+func (w *Window) loadGame(game *chess.Game, source *TextField) {
+	w.board.SetGame(game)
+	w.solution = nil
+	w.syncBoard(newBoardState(game), source)
+}
 
-	positions := game.Positions()
-	var pgn strings.Builder
-	var notation chess.AlgebraicNotation
-	for i, move := range game.Moves() {
-		if i&1 == 0 {
-			pgn.WriteString(strconv.Itoa(i/2 + 1))
-			pgn.WriteString(". ")
+func newBoardState(game *chess.Game) boardState {
+	return boardState{
+		game:     game,
+		plies:    len(game.Moves()),
+		position: game.Position().String(),
+	}
+}
+
+// syncBoard shows the board game in the fields,
+// except the one it's just loaded from
+func (w *Window) syncBoard(state boardState, source *TextField) {
+	w.synced = state
+
+	openingName, _ := w.index.SearchOpening(state.game)
+	w.opening.Set(openingName)
+	if book, ok := w.index.Transposition(state.game); ok {
+		if game, err := book.Game(); err == nil {
+			w.opening.SetTransposition(core.FormatPGN(game))
 		}
-		pgn.WriteString(notation.Encode(positions[i], move))
-		pgn.WriteString(" ")
 	}
-	w.pgn.SetText(pgn.String())
+
+	if source != w.fen {
+		w.fen.SetText(state.position)
+		w.fen.ShowError(nil)
+	}
+	if source != w.pgn {
+		w.pgn.SetText(core.FormatPGN(state.game))
+		w.pgn.ShowError(nil)
+	}
 }
 
 func (w *Window) handleSearch(gtx layout.Context) {
 	if w.search.button.Clicked(gtx) {
-		w.searching.Store(true)
+		filter, ok := w.searchFilter()
+		tolerance, okTolerance := parseNumberField(w.tolerance, 0, 40)
+		pattern, okPattern := w.searchPattern()
+		if !ok || !okTolerance || !okPattern {
+			gtx.Execute(op.InvalidateCmd{})
+			return
+		}
+		if w.searchStrategy.Selected() == core.BoardSearch && w.editor.Pattern().Empty() {
+			w.editing = true // nothing to search, draw the pattern first
+			w.editor.SetMode(PatternMode)
+			gtx.Execute(op.InvalidateCmd{})
+			return
+		}
+
+		w.startSearch(core.Query{
+			Game:         w.board.Game().Clone(),
+			Pattern:      pattern,
+			Board:        w.editor.Pattern(),
+			Material:     w.sameMaterial.Value,
+			Strategy:     w.searchStrategy.Selected(),
+			Tolerance:    uint8(tolerance),
+			PuzzleFilter: filter,
+			Sort:         w.sortOrder.Selected(),
+		}, nil)
+		gtx.Execute(op.InvalidateCmd{})
+	}
+}
+
+// handleSetup searches puzzles from the position set up in the editor
+func (w *Window) handleSetup(gtx layout.Context) {
+	if !w.editing || !w.editor.ShouldUse(gtx) {
+		return
+	}
+
+	position, ok := w.editor.Position()
+	filter, okFilter := w.searchFilter()
+	if !ok || !okFilter {
+		gtx.Execute(op.InvalidateCmd{})
+		return
+	}
 
-		maxMoves := w.movesCount.Selected()
-		turn := w.turn.Selected()
-		strategy := w.searchStrategy.Selected()
+	opt, err := chess.FEN(position.String())
+	if err != nil {
+		slog.Error("failed to load set up position", "fen", position.String(), "err", err)
+		return
+	}
+	w.loadGame(chess.NewGame(opt), nil)
+	w.editing = false
+	w.searchStrategy.Set(core.PositionSearch)
+
+	w.startSearch(core.Query{
+		Position:     position,
+		Strategy:     core.PositionSearch,
+		PuzzleFilter: filter,
+		Sort:         w.sortOrder.Selected(),
+	}, nil)
+	gtx.Execute(op.InvalidateCmd{})
+}
+
+// startSearch streams found puzzles to the results, if baseline
+// collection is given, only the puzzles missing in it are left
+func (w *Window) startSearch(query core.Query, baseline *core.Collection) {
+	w.searching.Store(true)
+	w.lastQuery = query
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.searchCancel = cancel
+
+	w.resultsMu.Lock()
+	w.results = make([]core.PuzzleData, 0, 1000)
+	w.resultsSort = core.NoSort // until search is finished
+	w.resultsMu.Unlock()
+	w.resultsLoaded.Store(false)
+
+	go func() {
+		defer cancel()
 
-		go func() {
+		start := time.Now()
+		refreshed := start
+		for puzzle := range w.index.SearchPuzzlesCtx(ctx, query) {
 			w.resultsMu.Lock()
-			defer w.resultsMu.Unlock()
+			w.results = append(w.results, puzzle)
+			w.resultsMu.Unlock()
+
+			// show the first results while searching
+			if baseline == nil && time.Since(refreshed) > ResultsRefreshInterval {
+				refreshed = time.Now()
+				w.resultsLoaded.Store(false)
+				w.window.Invalidate()
+			}
+		}
+
+		w.resultsMu.Lock()
+		slog.Info("puzzle search", "found", len(w.results), "took", time.Since(start), "aborted", ctx.Err() != nil)
+		if baseline != nil && ctx.Err() == nil {
+			added, removed := baseline.Diff(w.results)
+			w.results = added
+			w.collections.SetStatus(fmt.Sprintf("%d new, %d removed since %s",
+				len(added), len(removed), baseline.Saved.Format(time.DateOnly)), false)
+		}
+		core.SortPuzzles(w.results, query.Sort)
+		w.resultsSort = query.Sort
+		w.resultsMu.Unlock()
+
+		w.searching.Store(false)
+		w.resultsLoaded.Store(false)
+		w.window.Invalidate()
+	}()
+}
+
+// searchFilter reads filter options, highlighting invalid ones
+func (w *Window) searchFilter() (filter core.PuzzleFilter, ok bool) {
+	filter.Turn = w.turn.Selected().ToChess()
+	filter.MaxMoves = w.movesCount.Selected()
+
+	minRating, okMinRating := parseNumberField(w.minRating, 0, math.MaxUint16)
+	maxRating, okMaxRating := parseNumberField(w.maxRating, 0, math.MaxUint16)
+	minPopularity, okPopularity := parseNumberField(w.minPopularity, -100, 100)
+	filter.MinRating = uint16(minRating)
+	filter.MaxRating = uint16(maxRating)
+	if len(w.minPopularity.Text()) > 0 {
+		popularity := int8(minPopularity)
+		filter.MinPopularity = &popularity
+	}
+
+	themes, errThemes := core.ParseThemeNames(strings.Fields(w.themes.Text()))
+	w.themes.SetError(errThemes != nil)
+	excludedThemes, errExcluded := core.ParseThemeNames(strings.Fields(w.excludedThemes.Text()))
+	w.excludedThemes.SetError(errExcluded != nil)
+	filter.Themes = themes
+	filter.ExcludedThemes = excludedThemes
+
+	ok = okMinRating && okMaxRating && okPopularity && errThemes == nil && errExcluded == nil
+	return
+}
+
+// searchPattern parses the pattern for the pattern search only
+func (w *Window) searchPattern() (pattern core.MovePattern, ok bool) {
+	if w.searchStrategy.Selected() != core.PatternSearch {
+		w.pattern.ShowError(nil)
+		return pattern, true
+	}
+
+	pattern, err := core.ParseMovePattern(w.pattern.Text())
+	w.pattern.ShowError(err)
+	return pattern, err == nil
+}
 
-			start := time.Now()
-			results := w.index.SearchPuzzles(w.board.Game(), strategy, turn.ToChess(), maxMoves)
-			took := time.Since(start)
-			slog.Info("puzzle search", "found", len(results), "took", took)
+// parseNumberField treats empty field as zero
+func parseNumberField(field *TextField, min, max int) (n int, ok bool) {
+	text := field.Text()
+	if len(text) == 0 {
+		field.SetError(false)
+		return 0, true
+	}
+
+	n, err := strconv.Atoi(text)
+	ok = err == nil && min <= n && n <= max
+	field.SetError(!ok)
+	return
+}
+
+// formatNumberField leaves the field empty for zero
+func formatNumberField(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
 
-			w.results = make([]core.PuzzleData, len(results))
-			copy(w.results, results)
+func (w *Window) handleSort(gtx layout.Context) {
+	order := w.sortOrder.Selected()
 
-			w.searching.Store(false)
-			w.resultsLoaded.Store(false)
-			w.window.Invalidate()
-		}()
+	w.resultsMu.Lock()
+	defer w.resultsMu.Unlock()
 
+	if order != w.resultsSort {
+		core.SortPuzzles(w.results, order)
+		w.resultsSort = order
+		w.resultsLoaded.Store(false)
 		gtx.Execute(op.InvalidateCmd{})
 	}
 }
 
+func (w *Window) handleCancel(gtx layout.Context) {
+	if w.cancel.button.Clicked(gtx) && w.searchCancel != nil {
+		w.searchCancel()
+	}
+}
+
+func (w *Window) handlePreview(gtx layout.Context) {
+	puzzle, ok := w.puzzles.Selected(gtx)
+	if !ok {
+		return
+	}
+
+	game, ok := w.index.Games[puzzle.GameID]
+	if !ok {
+		slog.Warn("puzzle game not found", "puzzle", puzzle.ID, "game", puzzle.GameID)
+		return
+	}
+
+	preview, err := game.Replay(puzzle.Ply())
+	if err != nil {
+		slog.Error("failed to replay puzzle game", "puzzle", puzzle.ID, "err", err)
+		return
+	}
+
+	w.board.SetGame(preview)
+	w.solution = nil
+	w.window.Invalidate()
+}
+
+func (w *Window) handleSolve(gtx layout.Context) {
+	puzzle, ok := w.puzzles.Solving(gtx)
+	if !ok {
+		return
+	}
+
+	solution, ok := w.index.Solutions[puzzle.ID]
+	if !ok {
+		slog.Warn("puzzle solution not found", "puzzle", puzzle.ID)
+		return
+	}
+
+	game, err := solution.Start()
+	if err != nil {
+		slog.Error("failed to start puzzle", "puzzle", puzzle.ID, "err", err)
+		return
+	}
+
+	w.board.SetGame(game)
+	w.solution = &solution
+	w.solver = game.Position().Turn()
+	w.solvePuzzle = puzzle.ID
+	w.solveStart = time.Now()
+	w.solveRecorded = false
+	w.solveStatus.Text = "Find the best move for " + w.solver.Name()
+	w.solveStatus.Color = BlackColor
+	w.window.Invalidate()
+}
+
+// handleSolving checks the user moves and plays the opponent replies
+func (w *Window) handleSolving(gtx layout.Context) {
+	if w.solution == nil {
+		return
+	}
+
+	game := w.board.Game()
+	played := len(game.Moves())
+	status, err := w.solution.Verify(game)
+	if err != nil {
+		slog.Error("failed to verify puzzle solution", "err", err)
+		w.solution = nil
+		return
+	}
+
+	if len(game.Moves()) != played {
+		w.board.SetGame(game) // reply is played
+		w.window.Invalidate()
+	}
+
+	switch status {
+	case core.Solving:
+		w.solveStatus.Text = "Find the best move for " + w.solver.Name()
+		w.solveStatus.Color = BlackColor
+	case core.Solved:
+		w.solveStatus.Text = "Solved!"
+		w.solveStatus.Color = GreenColor
+		w.recordAttempt(true)
+	case core.Failed:
+		w.solveStatus.Text = "Wrong move, take it back"
+		w.solveStatus.Color = RedColor
+		w.recordAttempt(false)
+	}
+}
+
+// recordAttempt schedules the puzzle repetition by the
+// first outcome, retries after a wrong move don't count
+func (w *Window) recordAttempt(solved bool) {
+	if w.solveRecorded {
+		return
+	}
+	w.solveRecorded = true
+
+	now := time.Now()
+	w.training.Record(w.solvePuzzle, w.index.PuzzleOpening(w.solvePuzzle), core.TrainingAttempt{
+		Time:   now,
+		Solved: solved,
+		Took:   now.Sub(w.solveStart),
+	})
+	if err := resources.SaveConfig(TrainingFile, w.training); err != nil {
+		slog.Error("failed to save training queue", "err", err)
+	}
+}
+
+// handleDue shows the puzzles to repeat instead of the search results
+func (w *Window) handleDue(gtx layout.Context) {
+	if !w.due.button.Clicked(gtx) {
+		return
+	}
+
+	opening, _ := w.index.SearchOpening(w.board.Game())
+	ids := w.training.Due(time.Now(), opening)
+
+	w.resultsMu.Lock()
+	w.results = make([]core.PuzzleData, 0, len(ids))
+	for _, id := range ids {
+		if puzzle, ok := w.index.Puzzle(id); ok {
+			w.results = append(w.results, puzzle)
+		}
+	}
+	w.resultsSort = core.NoSort // due order
+	w.resultsMu.Unlock()
+
+	w.lastQuery = core.Query{} // not a search
+
+	w.sortOrder.Set(core.NoSort)
+	w.page = 0
+	w.resultsLoaded.Store(false)
+	gtx.Execute(op.InvalidateCmd{})
+}
+
+// handleExplorer plays the clicked opening line on the board
+func (w *Window) handleExplorer(gtx layout.Context) {
+	if query, ok := w.explorer.Searched(gtx); ok {
+		w.explorer.SetSuggestions(w.index.SearchOpeningNames(query, OpeningSuggestions))
+		w.window.Invalidate()
+	}
+
+	node, ok := w.explorer.Clicked(gtx)
+	if !ok {
+		return
+	}
+
+	if node.Opening != nil {
+		game, err := node.Opening.Game()
+		if err != nil {
+			slog.Error("failed to replay opening", "opening", node.Opening.Name, "err", err)
+			return
+		}
+		w.loadGame(game, nil)
+	}
+	w.window.Invalidate()
+}
+
+func (w *Window) handleOpen(gtx layout.Context) {
+	if puzzle, ok := w.puzzles.Opened(gtx); ok {
+		if err := OpenURL(puzzle.URL()); err != nil {
+			slog.Error("failed to open puzzle", "err", err)
+		}
+	}
+}
+
+func (w *Window) handleCollections(gtx layout.Context) {
+	name := w.collections.Name(gtx)
+	switch {
+	case w.collections.ShouldSave(gtx):
+		w.saveCollection(name)
+	case w.collections.ShouldLoad(gtx):
+		collection, query, ok := w.loadCollection(name)
+		if !ok {
+			break
+		}
+		w.resultsMu.Lock()
+		w.results = collection.Puzzles
+		core.SortPuzzles(w.results, query.Sort)
+		w.resultsSort = query.Sort
+		w.resultsMu.Unlock()
+		w.lastQuery = query
+		w.page = 0
+		w.resultsLoaded.Store(false)
+		w.collections.SetStatus(fmt.Sprintf("%d puzzles saved %s",
+			len(collection.Puzzles), collection.Saved.Format(time.DateOnly)), false)
+	case w.collections.ShouldDiff(gtx):
+		collection, query, ok := w.loadCollection(name)
+		if !ok {
+			break
+		}
+		w.page = 0
+		w.startSearch(query, &collection)
+	default:
+		return // do not refresh the screen
+	}
+	gtx.Execute(op.InvalidateCmd{})
+}
+
+func (w *Window) saveCollection(name string) {
+	search, err := core.NewSavedSearch(w.lastQuery)
+	if err != nil {
+		w.collections.SetStatus("Search puzzles first", true)
+		return
+	}
+
+	w.resultsMu.RLock()
+	collection := core.Collection{
+		Name:    name,
+		Saved:   time.Now(),
+		Search:  search,
+		Puzzles: slices.Clone(w.results),
+	}
+	w.resultsMu.RUnlock()
+
+	if err := core.SaveCollection(collection); err != nil {
+		slog.Error("failed to save collection", "name", name, "err", err)
+		w.collections.SetStatus("Failed to save collection", true)
+		return
+	}
+
+	names, err := core.ListCollections()
+	if err != nil {
+		slog.Error("failed to list collections", "err", err)
+	}
+	w.collections.SetNames(names)
+	w.collections.SetStatus(fmt.Sprintf("%d puzzles saved", len(collection.Puzzles)), false)
+}
+
+// loadCollection reads the collection and sets up
+// the board and the search options as they were saved
+func (w *Window) loadCollection(name string) (collection core.Collection, query core.Query, ok bool) {
+	collection, err := core.LoadCollection(name)
+	if err != nil {
+		slog.Error("failed to load collection", "name", name, "err", err)
+		w.collections.SetStatus("Collection not found", true)
+		return
+	}
+
+	query, err = collection.Search.Query()
+	if err != nil {
+		slog.Error("failed to parse saved search", "name", name, "err", err)
+		w.collections.SetStatus("Invalid saved search", true)
+		return
+	}
+
+	w.board.SetGame(query.Game.Clone())
+	w.solution = nil
+	w.movesCount.Set(query.MaxMoves)
+	w.turn.Set(core.TurnFromChess(query.Turn))
+	w.searchStrategy.Set(query.Strategy)
+	w.tolerance.SetText(formatNumberField(int(query.Tolerance)))
+	w.pattern.SetText(query.Pattern.String())
+	w.sameMaterial.Value = query.Material
+	w.editor.SetPattern(query.Board)
+	w.minRating.SetText(formatNumberField(int(query.MinRating)))
+	w.maxRating.SetText(formatNumberField(int(query.MaxRating)))
+	if query.MinPopularity != nil {
+		w.minPopularity.SetText(strconv.Itoa(int(*query.MinPopularity)))
+	} else {
+		w.minPopularity.SetText("")
+	}
+	w.themes.SetText(query.Themes.String())
+	w.excludedThemes.SetText(query.ExcludedThemes.String())
+	w.sortOrder.Set(query.Sort)
+
+	return collection, query, true
+}
+
+func (w *Window) handleExport(gtx layout.Context) {
+	if !w.export.ShouldExport(gtx) {
+		return
+	}
+
+	format := w.export.Format()
+	w.resultsMu.RLock()
+	puzzles := slices.Clone(w.results)
+	w.resultsMu.RUnlock()
+
+	filename, err := w.exportPuzzles(format, puzzles)
+	if err != nil {
+		slog.Error("failed to export puzzles", "err", err)
+		w.export.SetStatus("Export failed")
+	} else {
+		slog.Info("exported puzzles", "file", filename, "count", len(puzzles))
+		status := "Saved to " + filename
+		if format == core.StudyExport && len(puzzles) > core.StudyMaxChapters {
+			status += fmt.Sprintf(", import it by %d chapters", core.StudyMaxChapters)
+		}
+		w.export.SetStatus(status)
+	}
+	gtx.Execute(op.InvalidateCmd{})
+}
+
+// exportPuzzles writes the file to the downloads dir if there is one
+func (w *Window) exportPuzzles(format core.ExportFormat, puzzles []core.PuzzleData) (string, error) {
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home dir: %w", err)
+	}
+	if downloads := filepath.Join(dir, "Downloads"); isDir(downloads) {
+		dir = downloads
+	}
+
+	filename := filepath.Join(dir, "cops-puzzles-"+time.Now().Format("20060102-150405")+format.Ext())
+	file, err := os.Create(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+
+	if err := w.index.ExportPuzzles(file, format, puzzles); err != nil {
+		return "", err
+	}
+	return filename, file.Close()
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func (w *Window) handlePagination(gtx layout.Context) {
+	w.resultsMu.RLock()
+	last := lastPage(len(w.results))
+	w.resultsMu.RUnlock()
+
+	page := min(w.page, last)
+	switch {
+	case w.pagination.ShouldGoFirst(gtx):
+		page = 0
+	case w.pagination.ShouldGoPrevious(gtx):
+		page = max(page-1, 0)
+	case w.pagination.ShouldGoNext(gtx):
+		page = min(page+1, last)
+	case w.pagination.ShouldGoLast(gtx):
+		page = last
+	default:
+		return // do not refresh the screen
+	}
+
+	w.page = page
+	w.resultsLoaded.Store(false)
+	w.window.Invalidate()
+}
+
+func lastPage(total int) int {
+	return max((total+PageSize-1)/PageSize-1, 0)
+}
+
 func (w *Window) layoutWidgets(gtx layout.Context) layout.Dimensions {
 	return layout.Flex{Axis: layout.Vertical, Alignment: layout.Middle}.Layout(gtx,
 		layout.Flexed(1, Pad(w.padding, func(gtx layout.Context) layout.Dimensions {
 			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
-				layout.Flexed(3, w.layoutBoardPane),
+				layout.Flexed(3, w.idleOnly(w.layoutBoardPane)),
 				layout.Flexed(2, w.layoutSearchPane),
 			)
 		})),
@@ -244,12 +921,23 @@ func (w *Window) layoutWidgets(gtx layout.Context) layout.Dimensions {
 func (w *Window) layoutBoardPane(gtx layout.Context) layout.Dimensions {
 	return layout.Flex{Axis: layout.Vertical, Alignment: layout.Middle, Spacing: layout.SpaceBetween}.Layout(gtx,
 		layout.Rigid(Pad(w.padding, w.opening.Layout)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if w.solution == nil {
+				return layout.Dimensions{}
+			}
+			return Pad(w.padding, w.solveStatus.Layout)(gtx)
+		}),
 		layout.Flexed(6, Pad(w.padding, func(gtx layout.Context) layout.Dimensions {
 			return widget.Border{
 				Color:        BlackColor,
 				CornerRadius: unit.Dp(1),
 				Width:        unit.Dp(1),
-			}.Layout(gtx, w.board.Layout)
+			}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+				if w.editing {
+					return w.editor.Layout(gtx)
+				}
+				return w.board.Layout(gtx)
+			})
 		})),
 		layout.Rigid(Pad(w.padding, w.fen.Layout)),
 		layout.Flexed(1, Pad(w.padding, w.pgn.Layout)),
@@ -262,31 +950,79 @@ func (w *Window) layoutSearchPane(gtx layout.Context) layout.Dimensions {
 	if !w.resultsLoaded.Load() {
 		w.resultsLoaded.Store(true)
 		w.resultsMu.Lock()
-		results := w.results
-		if len(results) > PageSize {
-			results = results[:PageSize]
-		}
-		var text strings.Builder
-		for _, puzzle := range results {
-			text.WriteString(puzzle.URL())
-			text.WriteRune('\n')
+		total := len(w.results)
+		from := min(w.page, lastPage(total)) * PageSize
+		to := min(from+PageSize, total)
+		rows := make([]ResultRow, 0, to-from)
+		for _, puzzle := range w.results[from:to] {
+			rows = append(rows, ResultRow{
+				Puzzle:  puzzle,
+				Opening: w.index.PuzzleOpening(puzzle.ID),
+			})
 		}
-		w.puzzles.SetText(text.String())
+		w.puzzles.SetRows(rows)
+		w.pagination.SetStatus(from, to, total)
 		w.resultsMu.Unlock()
 		gtx.Execute(op.InvalidateCmd{})
 	}
 
 	return layout.Flex{Axis: layout.Vertical, Alignment: layout.Middle, Spacing: layout.SpaceBetween}.Layout(gtx,
-		layout.Flexed(1, Pad(w.padding, w.puzzles.Layout)),
-		layout.Rigid(PadSides(w.padding, w.movesCount.Layout)),
-		layout.Rigid(PadSides(w.padding, w.turn.Layout)),
-		layout.Rigid(PadSides(w.padding, w.searchStrategy.Layout)),
+		layout.Rigid(PadSides(w.padding, w.pane.Layout)),
+		layout.Flexed(1, Pad(w.padding, func(gtx layout.Context) layout.Dimensions {
+			if w.pane.Selected() == OpeningsPane {
+				return w.idleOnly(w.explorer.Layout)(gtx)
+			}
+			return w.puzzles.Layout(gtx)
+		})),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if w.pane.Selected() == OpeningsPane {
+				return layout.Dimensions{}
+			}
+			return PadSides(w.padding, w.pagination.Layout)(gtx)
+		}),
+		layout.Rigid(PadSides(w.padding, w.idleOnly(w.movesCount.Layout))),
+		layout.Rigid(PadSides(w.padding, w.idleOnly(w.turn.Layout))),
+		layout.Rigid(PadSides(w.padding, w.idleOnly(w.searchStrategy.Layout))),
+		layout.Rigid(Pad(w.padding, w.idleOnly(w.tolerance.Layout))),
+		layout.Rigid(PadSides(w.padding, w.idleOnly(w.pattern.Layout))),
+		layout.Rigid(PadSides(w.padding, w.idleOnly(material.CheckBox(w.theme, &w.sameMaterial, "Same material in structure search").Layout))),
+		layout.Rigid(Pad(w.padding, w.idleOnly(func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+				layout.Flexed(1, w.minRating.Layout),
+				layout.Rigid(layout.Spacer{Width: w.padding}.Layout),
+				layout.Flexed(1, w.maxRating.Layout),
+				layout.Rigid(layout.Spacer{Width: w.padding}.Layout),
+				layout.Flexed(1, w.minPopularity.Layout),
+			)
+		}))),
+		layout.Rigid(Pad(w.padding, w.idleOnly(w.themes.Layout))),
+		layout.Rigid(Pad(w.padding, w.idleOnly(w.excludedThemes.Layout))),
+		layout.Rigid(PadSides(w.padding, w.idleOnly(w.sortOrder.Layout))),
+		layout.Rigid(Pad(w.padding, w.idleOnly(w.collections.Layout))),
+		layout.Rigid(PadSides(w.padding, w.idleOnly(w.export.Layout))),
 		layout.Rigid(Pad(w.padding, func(gtx layout.Context) layout.Dimensions {
-			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, layout.Flexed(1, w.search.Layout))
+			if w.searching.Load() {
+				return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, layout.Flexed(1, w.cancel.Layout))
+			}
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+				layout.Flexed(3, w.search.Layout),
+				layout.Rigid(layout.Spacer{Width: w.padding}.Layout),
+				layout.Flexed(1, w.due.Layout),
+			)
 		})),
 	)
 }
 
+// idleOnly disables the widget while search is running
+func (w *Window) idleOnly(widget layout.Widget) layout.Widget {
+	return func(gtx layout.Context) layout.Dimensions {
+		if w.searching.Load() {
+			gtx = gtx.Disabled()
+		}
+		return widget(gtx)
+	}
+}
+
 func (w *Window) layoutLoading(gtx layout.Context) layout.Dimensions {
 	return layout.Flex{Axis: layout.Vertical, Alignment: layout.Middle, Spacing: layout.SpaceAround}.Layout(gtx,
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {